@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+)
+
+// affectedPackages returns the packages of the module in the current working directory that are affected by the given files,
+// as paths relative to the current working directory, in the same form as the packages given to checkers.
+//
+// A package is directly affected by its own go files, its testdata, and the files it embeds,
+// and is then also affected if it, or its tests, import an affected package.
+//
+// It returns nil if every package should be considered affected,
+// such as when go.mod or go.sum are among the files, or if the packages could not be listed.
+func affectedPackages(ctx context.Context, files []string) map[string]bool {
+	for _, file := range files {
+		switch file {
+		case "go.mod", "go.sum":
+			Verbose("affected packages", "all, by ", file)
+			return nil
+		}
+	}
+
+	pwd, err := filepath.Abs(".")
+	if err != nil {
+		Warning("affected packages", err)
+		return nil
+	}
+
+	pkgs, err := goCmd.ListDeps(ctx, "./...")
+	if err != nil {
+		Warning("affected packages", "could not list dependencies: ", err)
+		return nil
+	}
+
+	changed := make(map[string]bool)
+	for _, file := range files {
+		changed[file] = true
+	}
+
+	relDirs := make(map[string]string)
+	importers := make(map[string][]string)
+
+	var queue []string
+	for _, pkg := range pkgs {
+		if pkg.DepOnly {
+			continue
+		}
+
+		rel, err := filepath.Rel(pwd, pkg.Dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		relDirs[pkg.ImportPath] = rel
+
+		for _, imports := range [][]string{pkg.Imports, pkg.TestImports, pkg.XTestImports} {
+			for _, imp := range imports {
+				importers[imp] = append(importers[imp], pkg.ImportPath)
+			}
+		}
+
+		if touchesPackage(rel, pkg, files, changed) {
+			Verbose("package is affected directly", rel)
+			queue = append(queue, pkg.ImportPath)
+		}
+	}
+
+	affected := make(map[string]bool)
+	for len(queue) > 0 {
+		importPath := queue[0]
+		queue = queue[1:]
+
+		rel, ok := relDirs[importPath]
+		if !ok || affected[rel] {
+			continue
+		}
+
+		affected[rel] = true
+		queue = append(queue, importers[importPath]...)
+	}
+
+	return affected
+}
+
+// touchesPackage returns true if any of the given files is a go file, testdata, or embedded file of the package in dir.
+func touchesPackage(dir string, pkg *GoPackage, files []string, changed map[string]bool) bool {
+	testdata := filepath.Join(dir, "testdata") + pathSep
+
+	for _, file := range files {
+		if strings.HasSuffix(file, ".go") && filepath.Dir(file) == dir {
+			return true
+		}
+
+		if strings.HasPrefix(file, testdata) {
+			return true
+		}
+	}
+
+	for _, embeds := range [][]string{pkg.EmbedFiles, pkg.TestEmbedFiles, pkg.XTestEmbedFiles} {
+		for _, embed := range embeds {
+			if changed[filepath.Join(dir, embed)] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/analysis/passes/appends"
+	"golang.org/x/tools/go/analysis/passes/asmdecl"
+	"golang.org/x/tools/go/analysis/passes/assign"
+	"golang.org/x/tools/go/analysis/passes/atomic"
+	"golang.org/x/tools/go/analysis/passes/bools"
+	"golang.org/x/tools/go/analysis/passes/buildtag"
+	"golang.org/x/tools/go/analysis/passes/cgocall"
+	"golang.org/x/tools/go/analysis/passes/composite"
+	"golang.org/x/tools/go/analysis/passes/copylock"
+	"golang.org/x/tools/go/analysis/passes/defers"
+	"golang.org/x/tools/go/analysis/passes/directive"
+	"golang.org/x/tools/go/analysis/passes/errorsas"
+	"golang.org/x/tools/go/analysis/passes/framepointer"
+	"golang.org/x/tools/go/analysis/passes/hostport"
+	"golang.org/x/tools/go/analysis/passes/httpresponse"
+	"golang.org/x/tools/go/analysis/passes/ifaceassert"
+	"golang.org/x/tools/go/analysis/passes/loopclosure"
+	"golang.org/x/tools/go/analysis/passes/lostcancel"
+	"golang.org/x/tools/go/analysis/passes/nilfunc"
+	"golang.org/x/tools/go/analysis/passes/printf"
+	"golang.org/x/tools/go/analysis/passes/shift"
+	"golang.org/x/tools/go/analysis/passes/sigchanyzer"
+	"golang.org/x/tools/go/analysis/passes/slog"
+	"golang.org/x/tools/go/analysis/passes/stdmethods"
+	"golang.org/x/tools/go/analysis/passes/stdversion"
+	"golang.org/x/tools/go/analysis/passes/stringintconv"
+	"golang.org/x/tools/go/analysis/passes/structtag"
+	"golang.org/x/tools/go/analysis/passes/testinggoroutine"
+	"golang.org/x/tools/go/analysis/passes/tests"
+	"golang.org/x/tools/go/analysis/passes/timeformat"
+	"golang.org/x/tools/go/analysis/passes/unmarshal"
+	"golang.org/x/tools/go/analysis/passes/unreachable"
+	"golang.org/x/tools/go/analysis/passes/unsafeptr"
+	"golang.org/x/tools/go/analysis/passes/unusedresult"
+	"golang.org/x/tools/go/analysis/passes/waitgroup"
+	"golang.org/x/tools/go/packages"
+)
+
+// vetAnalyzers are the same analysis passes that are run by `go vet`.
+var vetAnalyzers = []*analysis.Analyzer{
+	appends.Analyzer,
+	asmdecl.Analyzer,
+	assign.Analyzer,
+	atomic.Analyzer,
+	bools.Analyzer,
+	buildtag.Analyzer,
+	cgocall.Analyzer,
+	composite.Analyzer,
+	copylock.Analyzer,
+	defers.Analyzer,
+	directive.Analyzer,
+	errorsas.Analyzer,
+	framepointer.Analyzer,
+	hostport.Analyzer,
+	httpresponse.Analyzer,
+	ifaceassert.Analyzer,
+	loopclosure.Analyzer,
+	lostcancel.Analyzer,
+	nilfunc.Analyzer,
+	printf.Analyzer,
+	shift.Analyzer,
+	sigchanyzer.Analyzer,
+	slog.Analyzer,
+	stdmethods.Analyzer,
+	stdversion.Analyzer,
+	stringintconv.Analyzer,
+	structtag.Analyzer,
+	testinggoroutine.Analyzer,
+	tests.Analyzer,
+	timeformat.Analyzer,
+	unmarshal.Analyzer,
+	unreachable.Analyzer,
+	unsafeptr.Analyzer,
+	unusedresult.Analyzer,
+	waitgroup.Analyzer,
+}
+
+// lintAnalyzers are the analysis passes run by the lint check.
+var lintAnalyzers = append([]*analysis.Analyzer{docAnalyzer}, styleAnalyzers...)
+
+// godocRegexp matches the issues of exported identifiers that are missing a doc comment,
+// which are not shown with --no-godoc.
+var godocRegexp = regexp.MustCompile(" or be unexported$")
+
+// selectAnalyzers returns the analyzers turned on by the given settings.
+// As with `go vet`, if any analyzers are turned on, then only those are selected.
+func selectAnalyzers(all []*analysis.Analyzer, settings map[string]bool) []*analysis.Analyzer {
+	var only bool
+	for _, enabled := range settings {
+		only = only || enabled
+	}
+
+	var analyzers []*analysis.Analyzer
+	for _, a := range all {
+		enabled, ok := settings[a.Name]
+
+		switch {
+		case only && !enabled:
+			continue
+		case ok && !enabled:
+			continue
+		}
+
+		analyzers = append(analyzers, a)
+	}
+
+	return analyzers
+}
+
+// hasAnalyzer returns true if any of the analyzers has the given name.
+func hasAnalyzer(all []*analysis.Analyzer, name string) bool {
+	for _, a := range all {
+		if a.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func describeAnalyzer(all []*analysis.Analyzer, name string) string {
+	for _, a := range all {
+		if a.Name == name {
+			doc, _, _ := strings.Cut(a.Doc, "\n\n")
+			return strings.Join(strings.Fields(doc), " ")
+		}
+	}
+
+	return ""
+}
+
+type loadedPackages struct {
+	pkgs []*packages.Package
+	err  error
+}
+
+// loadPackages loads the packages of the target, with their tests, fully type checked for analysis.
+// The packages are loaded only once per target.
+func loadPackages(ctx context.Context, t *Target) ([]*packages.Package, error) {
+	loaded := t.loaded.Get(func() loadedPackages {
+		cfg := &packages.Config{
+			Context: ctx,
+			Mode:    packages.LoadAllSyntax,
+			Dir:     t.Dir,
+			Env:     withoutGitRepoEnv(os.Environ()),
+			Tests:   true,
+		}
+
+		Verbose("loading packages for analysis…")
+
+		pkgs, err := packages.Load(cfg, relativePackages(t.Packages)...)
+		return loadedPackages{pkgs, err}
+	})
+
+	return loaded.pkgs, loaded.err
+}
+
+// analyze runs the given analyzers in-process on the packages of the target,
+// and returns their diagnostics as findings with the name of each analyzer as the rule.
+// Errors loading or type checking the packages are returned as findings with the rule "typecheck".
+func analyze(ctx context.Context, t *Target, analyzers []*analysis.Analyzer, typeErrors bool) []Finding {
+	if len(t.Packages) == 0 || len(analyzers) == 0 {
+		return nil
+	}
+
+	pkgs, err := loadPackages(ctx, t)
+	if err != nil {
+		return []Finding{{
+			Rule:    "typecheck",
+			Message: err.Error(),
+		}}
+	}
+
+	var findings []Finding
+	seen := make(map[string]bool)
+
+	add := func(f Finding) {
+		key := fmt.Sprintf("%s\x00%s:%d:%d\x00%s", f.Rule, f.File, f.Line, f.Column, f.Message)
+		if seen[key] {
+			// the same issue is found in both a package and its test variant.
+			return
+		}
+		seen[key] = true
+
+		findings = append(findings, f)
+	}
+
+	position := func(pos token.Position, f Finding) (Finding, bool) {
+		if pos.Filename == "" {
+			return f, true
+		}
+
+		rel, err := filepath.Rel(t.Dir, pos.Filename)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			// such as the generated main of a test binary.
+			return f, false
+		}
+
+		f.File = rel
+		f.Line = pos.Line
+		f.Column = pos.Column
+
+		return f, true
+	}
+
+	var hasErrors bool
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, pkgErr := range pkg.Errors {
+			hasErrors = true
+
+			if typeErrors {
+				f := parsePosition("typecheck", pkgErr.Error())
+
+				if f.File != "" {
+					if rel, err := filepath.Rel(t.Dir, f.File); err == nil && !strings.HasPrefix(rel, "..") {
+						f.File = rel
+					}
+				}
+
+				add(f)
+			}
+		}
+	})
+
+	graph, err := checker.Analyze(analyzers, pkgs, nil)
+	if err != nil {
+		return append(findings, Finding{
+			Rule:    "analysis",
+			Message: err.Error(),
+		})
+	}
+
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			// analysis is expected to be skipped for packages with errors, or that depend on them.
+			if hasErrors {
+				Verbose(act.Analyzer.Name, act.Package.PkgPath, ": ", act.Err)
+			} else {
+				Warning(act.Analyzer.Name, act.Package.PkgPath, ": ", act.Err)
+			}
+
+			continue
+		}
+
+		for _, d := range act.Diagnostics {
+			f := Finding{
+				Rule:    act.Analyzer.Name,
+				Message: d.Message,
+			}
+
+			if len(d.SuggestedFixes) > 0 && d.SuggestedFixes[0].Message != "" {
+				f.Fix = &Fix{
+					Description: d.SuggestedFixes[0].Message,
+				}
+			}
+
+			if f, ok := position(act.Package.Fset.Position(d.Pos), f); ok {
+				add(f)
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+
+		if a.File != b.File {
+			return a.File < b.File
+		}
+
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+
+		return a.Column < b.Column
+	})
+
+	return findings
+}
+
+// vetChecker reports the issues found by the analysis passes of `go vet` in each package.
+// If a vettool is configured, it is run by `go vet` instead.
+type vetChecker struct{}
+
+func (vetChecker) Name() string { return "vet" }
+func (vetChecker) Scope() Scope { return ScopePackage }
+
+func (vetChecker) DescribeRule(rule string) string {
+	switch rule {
+	case "vet":
+		return "go packages must have no go vet issues"
+	case "typecheck":
+		return "go packages must type check"
+	}
+
+	return describeAnalyzer(vetAnalyzers, rule)
+}
+
+func (vetChecker) Run(ctx context.Context, t *Target) []Finding {
+	if len(t.Packages) == 0 {
+		return nil
+	}
+
+	if t.Config.Vet.Vettool != "" {
+		return vetTool(ctx, t)
+	}
+
+	return analyze(ctx, t, selectAnalyzers(vetAnalyzers, t.Config.Vet.Analyzers), true)
+}
+
+// vetTool runs `go vet` with the configured vettool, and parses its output.
+func vetTool(ctx context.Context, t *Target) []Finding {
+	lines := goCmd.Vet(ctx, relativePackages(t.Packages),
+		WithAnalyzers(t.Config.Vet.Analyzers),
+		WithVettool(t.Config.Vet.Vettool),
+	)
+
+	var findings []Finding
+
+	for _, line := range lines {
+		// continuation lines belong to the previous issue.
+		if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, " ") {
+			if n := len(findings); n > 0 {
+				findings[n-1].Message += "\n" + strings.TrimSpace(line)
+				continue
+			}
+		}
+
+		rule := "vet"
+		if rest, ok := strings.CutPrefix(line, "vet: "); ok {
+			rule, line = "typecheck", rest
+		}
+
+		f := parsePosition(rule, line)
+		if f.File != "" {
+			f.File = filepath.Clean(f.File)
+		}
+
+		findings = append(findings, f)
+	}
+
+	return findings
+}
+
+// lintChecker reports style issues and missing doc comments in each package.
+type lintChecker struct{}
+
+func (lintChecker) Name() string { return "lint" }
+func (lintChecker) Scope() Scope { return ScopePackage }
+
+func (lintChecker) DescribeRule(rule string) string {
+	return describeAnalyzer(lintAnalyzers, rule)
+}
+
+func (lintChecker) Run(ctx context.Context, t *Target) []Finding {
+	findings := analyze(ctx, t, lintAnalyzers, false)
+
+	if Flags.NoGodoc {
+		findings = slices.DeleteFunc(findings, func(f Finding) bool {
+			return f.Rule == docAnalyzer.Name && godocRegexp.MatchString(f.Message)
+		})
+	}
+
+	return findings
+}
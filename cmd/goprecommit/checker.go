@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// Scope defines what a Checker checks, and thus how often it is run.
+type Scope int
+
+// Scopes that are defined:
+const (
+	// ScopeFile checkers are run once per module, with the go files to check.
+	ScopeFile Scope = iota
+	// ScopePackage checkers are run once per module, with the go packages to check.
+	ScopePackage
+	// ScopeModule checkers are run once per module, before any packages are listed.
+	ScopeModule
+	// ScopeRepo checkers are run once per repo, with all of the files to check.
+	ScopeRepo
+	// ScopeMessage checkers are run once per commit message, by the commit-msg hook, with the file of the message.
+	ScopeMessage
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeFile:
+		return "files"
+	case ScopePackage:
+		return "packages"
+	case ScopeModule:
+		return "module"
+	case ScopeRepo:
+		return "repo"
+	case ScopeMessage:
+		return "message"
+	}
+
+	return fmt.Sprintf("Scope(%d)", int(s))
+}
+
+// Target describes what a Checker should check.
+type Target struct {
+	Config *Config
+
+	// Dir is the absolute path of the current working directory,
+	// which all files and packages are relative to.
+	Dir string
+
+	// Prefix is the path of Dir relative to where goprecommit was run,
+	// it is prepended to the filename of every Finding.
+	Prefix string
+
+	// WorkTree is the absolute path of the directory in the working tree that corresponds to Dir.
+	// It is the same as Dir, unless checking a snapshot of the index.
+	WorkTree string
+
+	// Module is the go.mod being checked, or empty for ScopeRepo and ScopeMessage.
+	Module string
+
+	// GoModules is true if the module uses go modules, and ModBase is then the name of the module.
+	GoModules bool
+	ModBase   string
+
+	// Files are the files to check:
+	// for ScopeFile, only the go files of the module; for ScopeRepo, all files;
+	// for ScopeMessage, only the file of the commit message.
+	Files []string
+
+	// Branch is the branch being committed or pushed to, for ScopeRepo,
+	// or empty if HEAD is detached.
+	Branch string
+
+	// Packages are the go packages to check for ScopePackage.
+	Packages []string
+
+	// TestPackages are the go packages to test for ScopePackage,
+	// which includes every package affected by the files checked.
+	TestPackages []string
+
+	// broken is set by a ScopeModule checker when go cannot load the module,
+	// so that none of its packages can be listed.
+	broken bool
+
+	loaded once[loadedPackages]
+}
+
+// Checker defines a named check, which can be registered to be run by goprecommit.
+type Checker interface {
+	// Name returns the name of the check, which is also its name in the Config.
+	Name() string
+
+	// Scope returns the Scope of the check.
+	Scope() Scope
+
+	// Run checks the given target, and returns all of the issues found.
+	Run(ctx context.Context, t *Target) []Finding
+}
+
+// RuleDescriber may be implemented by a Checker to describe the rules of its findings.
+type RuleDescriber interface {
+	// DescribeRule returns a short human readable description of the given rule.
+	DescribeRule(rule string) string
+}
+
+var registry struct {
+	sync.Mutex
+	checkers []Checker
+}
+
+// RegisterChecker adds the given Checkers to the end of the registry.
+// Checkers of the same Scope are run in the order they were registered.
+//
+// It panics if a Checker with the same name has already been registered.
+func RegisterChecker(checkers ...Checker) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for _, c := range checkers {
+		for _, prev := range registry.checkers {
+			if prev.Name() == c.Name() {
+				panic(fmt.Sprintf("checker %q already registered", c.Name()))
+			}
+		}
+
+		registry.checkers = append(registry.checkers, c)
+	}
+}
+
+// Checkers returns all of the registered Checkers of the given Scope, in registration order.
+func Checkers(scope Scope) []Checker {
+	registry.Lock()
+	defer registry.Unlock()
+
+	var checkers []Checker
+	for _, c := range registry.checkers {
+		if c.Scope() == scope {
+			checkers = append(checkers, c)
+		}
+	}
+
+	return checkers
+}
+
+// hasChecker returns true if a Checker with the given name has been registered.
+func hasChecker(name string) bool {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for _, c := range registry.checkers {
+		if c.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describeRule returns a short description of the given rule of the named check.
+func describeRule(check, rule string) string {
+	registry.Lock()
+	defer registry.Unlock()
+
+	for _, c := range registry.checkers {
+		if c.Name() != check {
+			continue
+		}
+
+		if d, ok := c.(RuleDescriber); ok {
+			if desc := d.DescribeRule(rule); desc != "" {
+				return desc
+			}
+		}
+	}
+
+	if rule == "" {
+		return check
+	}
+
+	return check + ": " + rule
+}
+
+// runCheckers runs every enabled Checker of the given Scope against the target,
+// and reports all of their findings.
+// It returns the number of findings that should block the commit.
+func runCheckers(ctx context.Context, scope Scope, t *Target) int {
+	switch scope {
+	case ScopeFile:
+		if len(t.Files) == 0 {
+			return 0
+		}
+
+	case ScopePackage:
+		if len(t.Packages) == 0 && len(t.TestPackages) == 0 {
+			return 0
+		}
+	}
+
+	var issues int
+
+	for _, c := range Checkers(scope) {
+		name := c.Name()
+
+		if !t.Config.Enabled(name) {
+			Verbose("check disabled", name)
+			continue
+		}
+
+		Verbose(name + " on " + scope.String() + "…")
+
+		findings := c.Run(ctx, t)
+
+		if fixer, ok := c.(Fixer); ok && Flags.Fix {
+			findings = fixer.Fix(ctx, t, findings)
+		}
+
+		var blocking int
+		for i := range findings {
+			f := &findings[i]
+
+			f.Check = name
+			f.Severity = t.Config.Severity(name)
+
+			if f.File != "" && t.Prefix != "" {
+				f.File = filepath.Join(t.Prefix, f.File)
+			}
+
+			if f.Blocks() {
+				blocking++
+			}
+
+			if Flags.Format == FormatText {
+				Report(*f)
+			}
+		}
+
+		results.AddCheck(t.Module, name, findings, blocking == 0)
+		issues += blocking
+	}
+
+	return issues
+}
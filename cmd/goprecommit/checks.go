@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	RegisterChecker(
+		tidyChecker{},
+		gofmtChecker{},
+		goimportsChecker{},
+		lintChecker{},
+		vetChecker{},
+		golangciLintChecker{},
+		staticcheckChecker{},
+		testChecker{},
+		eolChecker{},
+		branchChecker{},
+		commitMsgChecker{},
+	)
+}
+
+// tidyChecker reports the changes `go mod tidy` would make on modules using go modules,
+// without making them.
+type tidyChecker struct{}
+
+func (tidyChecker) Name() string { return "tidy" }
+func (tidyChecker) Scope() Scope { return ScopeModule }
+
+func (tidyChecker) DescribeRule(rule string) string {
+	switch rule {
+	case "failed":
+		return "go mod tidy must succeed"
+	case "tidy":
+		return "go.mod and go.sum must be tidy"
+	case "unstaged":
+		return "changes to go.mod and go.sum must be staged"
+	}
+
+	return ""
+}
+
+func (tidyChecker) Run(ctx context.Context, t *Target) []Finding {
+	if !t.GoModules {
+		return nil
+	}
+
+	diff, ok := goCmd.ModTidyDiff(ctx)
+	if !ok {
+		t.broken = true
+
+		return []Finding{{
+			Rule:    "failed",
+			File:    "go.mod",
+			Message: "go mod tidy failed: " + diff,
+		}}
+	}
+
+	var findings []Finding
+
+	diffs := splitDiff(diff)
+	for _, file := range []string{"go.mod", "go.sum"} {
+		unstaged := gitCmd.HasUnstagedChanges(ctx, filepath.Join(t.WorkTree, file))
+
+		if diff, ok := diffs[file]; ok {
+			f := Finding{
+				Rule:    "tidy",
+				File:    file,
+				Message: "not tidy",
+				Fix: &Fix{
+					Description: "go mod tidy",
+					Diff:        diff,
+				},
+			}
+
+			if unstaged {
+				f.Message = "not tidy, and has changes that are not staged"
+				f.Fix.Description = "go mod tidy && git add " + file
+			}
+
+			findings = append(findings, f)
+			continue
+		}
+
+		// When checking the working tree rather than a snapshot of the index,
+		// a tidy file can still be committed untidy, if its changes are not staged.
+		if unstaged && t.Dir == t.WorkTree {
+			findings = append(findings, Finding{
+				Rule:    "unstaged",
+				File:    file,
+				Message: "has changes that are not staged",
+				Fix: &Fix{
+					Description: "git add " + file,
+				},
+			})
+		}
+	}
+
+	return findings
+}
+
+// gofmtChecker reports go files that are not formatted according to `gofmt`.
+type gofmtChecker struct{}
+
+func (gofmtChecker) Name() string { return "gofmt" }
+func (gofmtChecker) Scope() Scope { return ScopeFile }
+
+func (gofmtChecker) DescribeRule(string) string { return "go files must be formatted with gofmt" }
+
+func (gofmtChecker) Run(ctx context.Context, t *Target) []Finding {
+	var findings []Finding
+
+	for _, file := range gofmtCmd.List(ctx, t.Files) {
+		fix := &Fix{
+			Description: "gofmt -w " + file,
+		}
+
+		if Flags.Diff {
+			fix.Diff = gofmtCmd.Diff(ctx, file)
+		}
+
+		findings = append(findings, Finding{
+			Rule:    "format",
+			File:    file,
+			Message: "not formatted",
+			Fix:     fix,
+		})
+	}
+
+	return findings
+}
+
+// goimportsChecker reports go files that are not formatted according to `goimports`.
+// Files already reported by an enabled gofmtChecker are not reported again.
+type goimportsChecker struct{}
+
+func (goimportsChecker) Name() string { return "goimports" }
+func (goimportsChecker) Scope() Scope { return ScopeFile }
+
+func (goimportsChecker) DescribeRule(string) string {
+	return "go imports must be formatted with goimports"
+}
+
+func (goimportsChecker) Run(ctx context.Context, t *Target) []Finding {
+	installTool(ctx, t.Config, &goimportsCmd.command, goimportsPkg, goimportsVersion)
+
+	inGofmt := make(map[string]bool)
+
+	if t.Config.Enabled("gofmt") {
+		for _, file := range gofmtCmd.List(ctx, t.Files) {
+			inGofmt[file] = true
+		}
+	}
+
+	var findings []Finding
+
+	for _, file := range goimportsCmd.List(ctx, t.Files) {
+		if inGofmt[file] {
+			continue
+		}
+
+		fix := &Fix{
+			Description: "goimports -w " + file,
+		}
+
+		if Flags.Diff {
+			fix.Diff = goimportsCmd.Diff(ctx, file)
+		}
+
+		findings = append(findings, Finding{
+			Rule:    "imports",
+			File:    file,
+			Message: "imports not formatted",
+			Fix:     fix,
+		})
+	}
+
+	return findings
+}
+
+// eolChecker reports files that do not end with an EOL.
+type eolChecker struct{}
+
+func (eolChecker) Name() string { return "eol" }
+func (eolChecker) Scope() Scope { return ScopeRepo }
+
+func (eolChecker) DescribeRule(string) string { return "files must end with an EOL" }
+
+func (eolChecker) Run(ctx context.Context, t *Target) []Finding {
+	var findings []Finding
+
+	for _, file := range t.Files {
+		select {
+		case <-ctx.Done():
+			Exit(1)
+		default:
+		}
+
+		if t.Config.SkipEOL(file) {
+			continue
+		}
+
+		if strings.Contains(file, ".") {
+			if !endsWithEOL(file) {
+				findings = append(findings, Finding{
+					Rule:    "eol",
+					File:    file,
+					Message: "file doesn't end with EOL",
+				})
+			}
+		}
+	}
+
+	return findings
+}
+
+func endsWithEOL(filename string) bool {
+	f, err := os.Open(filename)
+	if err != nil {
+		Error("check eol", err)
+		return false
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		Error("check eol", err)
+		return false
+	}
+	if fi.Size() == 0 {
+		// a completely empty file is zero lines of text, and thus valid.
+		return true
+	}
+
+	if _, err := f.Seek(-1, os.SEEK_END); err != nil {
+		Error("check eol", err)
+		return false
+	}
+
+	var buf [1]byte
+
+	if _, err := f.Read(buf[:]); err != nil {
+		Error("check eol", err)
+		return false
+	}
+
+	return buf[0] == '\n'
+}
+
+// branchChecker reports commits to the head branch of a remote, or any other protected branch,
+// and commits to branches with names that do not match the configured branch name pattern.
+type branchChecker struct{}
+
+func (branchChecker) Name() string { return "branch" }
+func (branchChecker) Scope() Scope { return ScopeRepo }
+
+func (branchChecker) DescribeRule(rule string) string {
+	switch rule {
+	case "protected":
+		return "protected branches must not be committed to"
+	case "name":
+		return "branch names must match the configured pattern"
+	}
+
+	return ""
+}
+
+func (branchChecker) Run(ctx context.Context, t *Target) []Finding {
+	branch := t.Branch
+	if branch == "" {
+		Verbose("branch", "HEAD is detached")
+		return nil
+	}
+
+	for _, head := range gitCmd.HeadBranches(ctx, t.Config.ProtectedRemotes) {
+		if branch == head {
+			return []Finding{{
+				Rule:    "protected",
+				Message: "do not commit to " + branch,
+				Fix: &Fix{
+					Description: "git switch -c <new-branch>",
+				},
+			}}
+		}
+	}
+
+	if pattern, ok := t.Config.IsProtected(branch); ok {
+		msg := "do not commit to " + branch
+		if pattern != branch {
+			msg += ", it is protected by " + pattern
+		}
+
+		return []Finding{{
+			Rule:    "protected",
+			Message: msg,
+			Fix: &Fix{
+				Description: "git switch -c <new-branch>",
+			},
+		}}
+	}
+
+	if !t.Config.ValidBranchName(branch) {
+		return []Finding{{
+			Rule:    "name",
+			Message: "branch name " + branch + " does not match " + t.Config.BranchName,
+			Fix: &Fix{
+				Description: "git branch -m <new-name>",
+			},
+		}}
+	}
+
+	return nil
+}
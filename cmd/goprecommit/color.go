@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 )
 
 func withColor(color, context string, a []any) string {
@@ -55,3 +56,46 @@ func Warning(context string, a ...any) {
 func Error(context any, a ...any) {
 	out.Short(withColor("91", fmt.Sprint(context), a))
 }
+
+// Report prints a Finding in a color according to its severity.
+func Report(f Finding) {
+	if f.Blocks() {
+		Error(f.Check, f)
+	} else {
+		Warning(f.Check, f)
+	}
+
+	if f.Fix != nil && f.Fix.Description != "" {
+		Info(f.Check, "suggested fix: ", f.Fix.Description)
+	}
+
+	if f.Fix != nil && f.Fix.Diff != "" {
+		Diff(f.Fix.Diff, Flags.DiffLines)
+	}
+}
+
+// Diff prints a unified diff with removed lines in red, added lines in green, and hunk headers in cyan.
+// Unless the noise level is verbose, no more than `limit` lines are printed.
+func Diff(diff string, limit int) {
+	lines := strings.Split(strings.TrimRight(diff, "\n"), "\n")
+
+	if out < NoiseLevelVerbose && limit >= 0 && len(lines) > limit {
+		omitted := len(lines) - limit
+		lines = append(lines[:limit:limit], fmt.Sprintf("… %d more lines, use --verbose to see all", omitted))
+	}
+
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"), strings.HasPrefix(line, "diff "):
+			out.Info(withColor("37", "", []any{line}))
+		case strings.HasPrefix(line, "+"):
+			out.Info(withColor("92", "", []any{line}))
+		case strings.HasPrefix(line, "-"):
+			out.Info(withColor("91", "", []any{line}))
+		case strings.HasPrefix(line, "@@"):
+			out.Info(withColor("96", "", []any{line}))
+		default:
+			out.Info(line)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// messageLine is a single line of a commit message, with its line number in the file of the message.
+type messageLine struct {
+	n    int
+	text string
+}
+
+// scissors marks the start of the diff that `git commit --verbose` appends to a commit message,
+// after the comment string.
+const scissors = " ------------------------ >8 ------------------------"
+
+// readCommitMsg reads the commit message from the given file, in the same way that git cleans it up:
+// comment lines, and everything after the scissors line, are removed,
+// as are leading and trailing blank lines.
+func readCommitMsg(filename, commentChar string) ([]messageLine, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []messageLine
+
+	for i, text := range strings.Split(string(data), "\n") {
+		text = strings.TrimRight(text, "\r")
+
+		if text == commentChar+scissors {
+			break
+		}
+
+		if strings.HasPrefix(text, commentChar) {
+			continue
+		}
+
+		lines = append(lines, messageLine{
+			n:    i + 1,
+			text: strings.TrimRightFunc(text, isSpace),
+		})
+	}
+
+	for len(lines) > 0 && lines[0].text == "" {
+		lines = lines[1:]
+	}
+
+	for len(lines) > 0 && lines[len(lines)-1].text == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines, nil
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// generatedMsgPrefixes start commit messages that are generated by git, which are not validated.
+var generatedMsgPrefixes = []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "}
+
+var (
+	conventionalHeaderRegexp = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]+)\))?(!)?: (\S.*)$`)
+	trailerRegexp            = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*|BREAKING CHANGE): `)
+)
+
+// splitTrailers splits the trailers from the end of the body of a commit message.
+// The trailers are the last paragraph, if every line of it is a trailer, or continues the one before.
+func splitTrailers(body []messageLine) (text, trailers []messageLine) {
+	start := len(body)
+	for start > 0 && body[start-1].text != "" {
+		start--
+	}
+
+	if start == len(body) {
+		return body, nil
+	}
+
+	for i, line := range body[start:] {
+		if trailerRegexp.MatchString(line.text) {
+			continue
+		}
+
+		if i > 0 && strings.IndexFunc(line.text, isSpace) == 0 {
+			continue
+		}
+
+		return body, nil
+	}
+
+	return body[:start], body[start:]
+}
+
+// commitMsgChecker reports commit messages that do not follow Conventional Commits,
+// or that do not have the configured form.
+type commitMsgChecker struct{}
+
+func (commitMsgChecker) Name() string { return "commit-msg" }
+func (commitMsgChecker) Scope() Scope { return ScopeMessage }
+
+func (commitMsgChecker) DescribeRule(rule string) string {
+	switch rule {
+	case "header":
+		return "commit messages must start with a Conventional Commits header"
+	case "type":
+		return "commit messages must have one of the configured types"
+	case "scope":
+		return "commit messages must have one of the configured scopes"
+	case "subject-length":
+		return "the header of commit messages must not be too long"
+	case "blank-line":
+		return "the header of commit messages must be followed by a blank line"
+	case "body-width":
+		return "the body of commit messages must be wrapped"
+	case "trailer":
+		return "commit messages must have the configured trailers"
+	}
+
+	return ""
+}
+
+func (commitMsgChecker) Run(ctx context.Context, t *Target) []Finding {
+	var findings []Finding
+
+	for _, file := range t.Files {
+		lines, err := readCommitMsg(file, gitCmd.CommentChar(ctx))
+		if err != nil {
+			Error("commit-msg", err)
+			Exit(1)
+		}
+
+		for _, f := range checkCommitMsg(&t.Config.CommitMsg, lines) {
+			f.File = file
+			findings = append(findings, f)
+		}
+	}
+
+	return findings
+}
+
+// checkCommitMsg checks the given lines of a commit message against the configuration.
+func checkCommitMsg(cfg *CommitMsgConfig, lines []messageLine) []Finding {
+	if len(lines) == 0 {
+		// git aborts the commit itself.
+		return nil
+	}
+
+	header := lines[0]
+
+	for _, prefix := range generatedMsgPrefixes {
+		if strings.HasPrefix(header.text, prefix) {
+			Verbose("commit-msg", "not checking generated message")
+			return nil
+		}
+	}
+
+	var findings []Finding
+
+	add := func(rule string, line messageLine, msg string) {
+		findings = append(findings, Finding{
+			Rule:    rule,
+			Line:    line.n,
+			Message: msg,
+		})
+	}
+
+	if m := conventionalHeaderRegexp.FindStringSubmatch(header.text); m == nil {
+		add("header", header, `header must have the form "type(scope)!: subject", got: `+header.text)
+	} else {
+		typ, scope := m[1], m[2]
+
+		if !containsFold(cfg.Types, typ) {
+			add("type", header, fmt.Sprintf("type %q must be one of: %s", typ, strings.Join(cfg.Types, ", ")))
+		}
+
+		if scope != "" && len(cfg.Scopes) > 0 && !containsFold(cfg.Scopes, scope) {
+			add("scope", header, fmt.Sprintf("scope %q must be one of: %s", scope, strings.Join(cfg.Scopes, ", ")))
+		}
+	}
+
+	if n := utf8.RuneCountInString(header.text); cfg.SubjectLength > 0 && n > cfg.SubjectLength {
+		add("subject-length", header, fmt.Sprintf("header is %d characters long, must be at most %d", n, cfg.SubjectLength))
+	}
+
+	if len(lines) > 1 && lines[1].text != "" {
+		add("blank-line", lines[1], "header must be followed by a blank line")
+	}
+
+	body, trailers := splitTrailers(lines[1:])
+
+	for _, line := range body {
+		if cfg.BodyWidth <= 0 {
+			break
+		}
+
+		n := utf8.RuneCountInString(line.text)
+		if n <= cfg.BodyWidth {
+			continue
+		}
+
+		// indented lines, such as quoted output, and lines without spaces, such as URLs, cannot be wrapped.
+		if strings.IndexFunc(line.text, isSpace) <= 0 {
+			continue
+		}
+
+		add("body-width", line, fmt.Sprintf("line is %d characters long, must be wrapped at %d", n, cfg.BodyWidth))
+	}
+
+	for _, want := range cfg.Trailers {
+		var found bool
+		for _, line := range trailers {
+			if key, _, ok := strings.Cut(line.text, ": "); ok && strings.EqualFold(key, want) {
+				found = true
+				break
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		f := Finding{
+			Rule:    "trailer",
+			Message: "missing trailer: " + want,
+			Fix: &Fix{
+				Description: "git commit --trailer '" + want + ": …'",
+			},
+		}
+
+		if strings.EqualFold(want, "Signed-off-by") {
+			f.Fix.Description = "git commit --signoff"
+		}
+
+		findings = append(findings, f)
+	}
+
+	return findings
+}
+
+func containsFold(list []string, s string) bool {
+	for _, elem := range list {
+		if strings.EqualFold(elem, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// commitMsgHook runs goprecommit as the commit-msg hook, which validates the commit message in the given file,
+// and exits with a failure if it is not valid.
+func commitMsgHook(ctx context.Context, args []string) {
+	if len(args) != 1 {
+		Error("commit-msg", "expected only the file of the commit message")
+		Exit(2)
+	}
+
+	if !gitCmd.InRepo(ctx) {
+		Error("not in git repo")
+		Exit(1)
+	}
+
+	cfg, err := DefaultConfig().LoadConfig(gitCmd.TopLevel(ctx))
+	if err != nil {
+		Error("config", err)
+		Exit(1)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		Error("getwd", err)
+		Exit(1)
+	}
+
+	target := &Target{
+		Config:   cfg,
+		Dir:      dir,
+		WorkTree: dir,
+		Files:    args,
+	}
+
+	issues := runCheckers(ctx, ScopeMessage, target)
+
+	writeResults()
+
+	if issues > 0 {
+		Exit(1)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeCommitMsg writes the commit message to a file in a temporary directory, and returns its filename.
+func writeCommitMsg(t *testing.T, msg string) string {
+	t.Helper()
+
+	filename := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	if err := os.WriteFile(filename, []byte(msg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return filename
+}
+
+// messageLines returns the lines of the text, numbered from 1.
+func messageLines(text string) []messageLine {
+	var lines []messageLine
+	for i, line := range strings.Split(text, "\n") {
+		lines = append(lines, messageLine{n: i + 1, text: line})
+	}
+
+	return lines
+}
+
+func TestReadCommitMsg(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		want []messageLine
+	}{
+		{
+			name: "empty",
+			msg:  "",
+			want: []messageLine{},
+		},
+		{
+			name: "only comments",
+			msg:  "\n# Please enter the commit message for your changes.\n#\n",
+			want: []messageLine{},
+		},
+		{
+			name: "header and body",
+			msg:  "feat: add x\n\nBody text.\n",
+			want: []messageLine{{1, "feat: add x"}, {2, ""}, {3, "Body text."}},
+		},
+		{
+			name: "comments removed, line numbers kept",
+			msg:  "# comment\nfeat: add x\n# comment\n\nBody text.\n# comment\n",
+			want: []messageLine{{2, "feat: add x"}, {4, ""}, {5, "Body text."}},
+		},
+		{
+			name: "leading and trailing blank lines",
+			msg:  "\n\nfeat: add x\n\n\n",
+			want: []messageLine{{3, "feat: add x"}},
+		},
+		{
+			name: "trailing whitespace and carriage returns",
+			msg:  "feat: add x \t\r\n\r\nBody text.  \r\n",
+			want: []messageLine{{1, "feat: add x"}, {2, ""}, {3, "Body text."}},
+		},
+		{
+			name: "scissors",
+			msg:  "feat: add x\n\n#" + scissors + "\ndiff --git a/x b/x\n+x\n",
+			want: []messageLine{{1, "feat: add x"}},
+		},
+		{
+			name: "indented lines kept",
+			msg:  "feat: add x\n\n    quoted output\n",
+			want: []messageLine{{1, "feat: add x"}, {2, ""}, {3, "    quoted output"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readCommitMsg(writeCommitMsg(t, tt.msg), "#")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readCommitMsg() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCommitMsgCommentChar(t *testing.T) {
+	got, err := readCommitMsg(writeCommitMsg(t, "feat: add x\n\n# not a comment\n; comment\n"), ";")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []messageLine{{1, "feat: add x"}, {2, ""}, {3, "# not a comment"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readCommitMsg() = %v, want %v", got, want)
+	}
+}
+
+func TestSplitTrailers(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantText     int
+		wantTrailers int
+	}{
+		{
+			name:     "no body",
+			body:     "",
+			wantText: 1,
+		},
+		{
+			name:     "only text",
+			body:     "\nSome text.",
+			wantText: 2,
+		},
+		{
+			name:         "only trailers",
+			body:         "\nSigned-off-by: A <a@example.com>",
+			wantText:     1,
+			wantTrailers: 1,
+		},
+		{
+			name:         "text and trailers",
+			body:         "\nSome text.\n\nRefs: PLAT-1\nSigned-off-by: A <a@example.com>",
+			wantText:     3,
+			wantTrailers: 2,
+		},
+		{
+			name:         "breaking change",
+			body:         "\nSome text.\n\nBREAKING CHANGE: x is gone",
+			wantText:     3,
+			wantTrailers: 1,
+		},
+		{
+			name:         "continued trailer",
+			body:         "\nSome text.\n\nBREAKING CHANGE: x is gone,\n  use y instead",
+			wantText:     3,
+			wantTrailers: 2,
+		},
+		{
+			name:     "last paragraph is not all trailers",
+			body:     "\nSome text.\n\nRefs: PLAT-1\nand more text.",
+			wantText: 5,
+		},
+		{
+			name:     "last paragraph starts with continuation",
+			body:     "\nSome text.\n\n  indented: text",
+			wantText: 4,
+		},
+		{
+			name:     "text that is not a trailer",
+			body:     "\nSee: the docs for more.\nBut this is text.",
+			wantText: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := messageLines(tt.body)
+
+			text, trailers := splitTrailers(body)
+			if len(text) != tt.wantText || len(trailers) != tt.wantTrailers {
+				t.Fatalf("splitTrailers() = %d lines of text and %d trailers, want %d and %d", len(text), len(trailers), tt.wantText, tt.wantTrailers)
+			}
+
+			if got := append(append([]messageLine(nil), text...), trailers...); !reflect.DeepEqual(got, body) {
+				t.Errorf("splitTrailers() = %v and %v, which is not the body %v", text, trailers, body)
+			}
+		})
+	}
+}
+
+func TestCheckCommitMsg(t *testing.T) {
+	base := DefaultConfig().CommitMsg
+	base.Scopes = []string{"api", "cli"}
+	base.SubjectLength = 30
+	base.BodyWidth = 40
+
+	signoff := base
+	signoff.Trailers = []string{"Signed-off-by"}
+
+	tests := []struct {
+		name string
+		cfg  *CommitMsgConfig
+		msg  string
+		want []string
+	}{
+		{
+			name: "empty",
+			msg:  "",
+		},
+		{
+			name: "valid",
+			msg:  "feat: add x",
+		},
+		{
+			name: "valid with scope and breaking",
+			msg:  "feat(api)!: add x\n\nBody text.",
+		},
+		{
+			name: "type is case insensitive",
+			msg:  "Fix(CLI): add x",
+		},
+		{
+			name: "not conventional",
+			msg:  "add x",
+			want: []string{"header:1"},
+		},
+		{
+			name: "no space after colon",
+			msg:  "feat:add x",
+			want: []string{"header:1"},
+		},
+		{
+			name: "empty subject",
+			msg:  "feat: ",
+			want: []string{"header:1"},
+		},
+		{
+			name: "unknown type",
+			msg:  "feature: add x",
+			want: []string{"type:1"},
+		},
+		{
+			name: "unknown scope",
+			msg:  "feat(db): add x",
+			want: []string{"scope:1"},
+		},
+		{
+			name: "header too long",
+			msg:  "feat: add a feature that is long",
+			want: []string{"subject-length:1"},
+		},
+		{
+			name: "header length in characters",
+			msg:  "feat: ünïcödé ünïcödé ünïcödé",
+		},
+		{
+			name: "no blank line",
+			msg:  "feat: add x\nBody text.",
+			want: []string{"blank-line:2"},
+		},
+		{
+			name: "body too wide",
+			msg:  "feat: add x\n\nThis line of the body is far too wide for the width.\nThis one is fine.",
+			want: []string{"body-width:3"},
+		},
+		{
+			name: "unwrappable lines",
+			msg:  "feat: add x\n\n    indented output that is far too wide for the width\nhttps://example.com/a/url/that/is/far/too/wide/for/the/width",
+		},
+		{
+			name: "trailers are not wrapped",
+			msg:  "feat: add x\n\nCo-authored-by: Someone With A Long Name <someone@example.com>",
+		},
+		{
+			name: "generated message",
+			msg:  "Merge branch 'feature' into main\nMore text",
+		},
+		{
+			name: "fixup",
+			msg:  "fixup! feat: add x",
+		},
+		{
+			name: "signed off",
+			cfg:  &signoff,
+			msg:  "feat: add x\n\nBody text.\n\nSigned-off-by: A <a@example.com>",
+		},
+		{
+			name: "trailer key is case insensitive",
+			cfg:  &signoff,
+			msg:  "feat: add x\n\nsigned-off-by: A <a@example.com>",
+		},
+		{
+			name: "not signed off",
+			cfg:  &signoff,
+			msg:  "feat: add x\n\nBody text.",
+			want: []string{"trailer:0"},
+		},
+		{
+			name: "signed off in text",
+			cfg:  &signoff,
+			msg:  "feat: add x\n\nSigned-off-by: A <a@example.com>\nBody text.",
+			want: []string{"trailer:0"},
+		},
+		{
+			name: "several findings",
+			msg:  "add a feature that is far too long\nBody text.",
+			want: []string{"header:1", "subject-length:1", "blank-line:2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg
+			if cfg == nil {
+				cfg = &base
+			}
+
+			var lines []messageLine
+			if tt.msg != "" {
+				lines = messageLines(tt.msg)
+			}
+
+			var got []string
+			for _, f := range checkCommitMsg(cfg, lines) {
+				got = append(got, fmt.Sprintf("%s:%d", f.Rule, f.Line))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkCommitMsg() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,613 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	flag "github.com/puellanivis/breton/lib/gnuflag"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFilename is the name of the configuration file looked for at the top of the repo, and next to each go.mod.
+const ConfigFilename = ".goprecommit.yaml"
+
+// Severity defines how a check’s issues should be treated.
+type Severity int
+
+// Severities that are defined, the zero value is the default severity of a check.
+const (
+	SeverityWarning Severity = iota + 1
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Severity) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "warning", "warn":
+		*s = SeverityWarning
+	case "error":
+		*s = SeverityError
+	default:
+		return fmt.Errorf("unknown severity: %q", text)
+	}
+
+	return nil
+}
+
+// CheckConfig is the configuration of a single named check.
+type CheckConfig struct {
+	// Enabled turns the check on or off, if unset the check is enabled.
+	Enabled *bool `yaml:"enabled"`
+
+	// Severity of the issues found by the check, if unset issues are errors.
+	// Only errors will block a commit, warnings are only reported.
+	Severity Severity `yaml:"severity"`
+}
+
+// VetConfig is the configuration of the vet check.
+type VetConfig struct {
+	// Analyzers turns each named `go vet` analyzer on or off.
+	// If any analyzers are turned on, then only those are run.
+	Analyzers map[string]bool `yaml:"analyzers"`
+
+	// Vettool is the path of an alternative analysis tool to be run by `go vet`.
+	// A relative path is relative to the directory of the configuration file.
+	Vettool string `yaml:"vettool"`
+}
+
+// CommitMsgConfig is the configuration of the commit-msg check.
+type CommitMsgConfig struct {
+	// Types are the types allowed in the header of a commit message.
+	Types []string `yaml:"types"`
+
+	// Scopes are the scopes allowed in the header of a commit message, if unset, any scope is allowed.
+	Scopes []string `yaml:"scopes"`
+
+	// SubjectLength is the maximum length of the header of a commit message.
+	SubjectLength int `yaml:"subject_length"`
+
+	// BodyWidth is the maximum length of each line of the body of a commit message.
+	BodyWidth int `yaml:"body_width"`
+
+	// Trailers are the trailers that every commit message must have, such as `Signed-off-by`.
+	Trailers []string `yaml:"trailers"`
+}
+
+// TicketConfig is the configuration of how the prepare-commit-msg hook adds the ticket of a branch to commit messages.
+type TicketConfig struct {
+	// Pattern is a regular expression that finds the ticket in the name of the branch, if unset, no ticket is added.
+	// If it has a capturing group, then the first group is the ticket.
+	Pattern string `yaml:"pattern"`
+
+	// Position is where the ticket is added to the commit message: as a `prefix` of the header, or as a `trailer`.
+	// In a Conventional Commits header, the prefix goes after the type, at the start of the subject.
+	Position string `yaml:"position"`
+
+	// Trailer is the key of the trailer that the ticket is added as.
+	Trailer string `yaml:"trailer"`
+}
+
+// ProfileConfig is a named profile of checks, selected by `--check-profile`, or by the hook being run.
+type ProfileConfig struct {
+	// Skip lists the checks that are not run in the profile, even if they are enabled.
+	Skip []string `yaml:"skip"`
+
+	// AllTests runs the tests of all packages, not only those affected by the files checked.
+	AllTests bool `yaml:"all_tests"`
+
+	// Short, Race, and Cover run the tests with `-short`, `-race`, and `-cover`.
+	Short bool `yaml:"short"`
+	Race  bool `yaml:"race"`
+	Cover bool `yaml:"cover"`
+}
+
+// Config describes the per-repository configuration of goprecommit.
+//
+// A Config is read from a ConfigFilename at the top of the repo,
+// and may be refined by a ConfigFilename next to each go.mod.
+type Config struct {
+	// ProtectedBranches may not be committed to, in addition to the head branches of the ProtectedRemotes.
+	// Each is a glob, or a regular expression if it is enclosed in slashes, such as `/^release-\d+$/`.
+	ProtectedBranches []string `yaml:"protected_branches"`
+
+	// ProtectedRemotes are the remotes whose head branches may not be committed to, if unset, every remote.
+	// If none of them has a known head branch, such as when there are no remotes,
+	// then `init.defaultBranch` may not be committed to instead.
+	ProtectedRemotes []string `yaml:"protected_remotes"`
+
+	// BranchName is a regular expression that the name of any branch committed to must match, if set.
+	BranchName string `yaml:"branch_name"`
+
+	// Vendor lists directory names that hold vendored code, which are never checked.
+	Vendor []string `yaml:"vendor"`
+
+	// GeneratedCodeMarker is a regular expression matching a line that marks a file as generated code.
+	GeneratedCodeMarker string `yaml:"generated_code_marker"`
+
+	// EOLSkip lists filename suffixes that are not checked for ending with an EOL.
+	EOLSkip []string `yaml:"eol_skip"`
+
+	// Checks configures each check by name:
+	// branch, commit-msg, eol, gofmt, goimports, golangci-lint, lint, staticcheck, test, tidy, vet.
+	// Checks that are enabled may still be skipped by a profile.
+	Checks map[string]CheckConfig `yaml:"checks"`
+
+	// Vet configures how `go vet` is run by the vet check.
+	Vet VetConfig `yaml:"vet"`
+
+	// CommitMsg configures how commit messages are validated by the commit-msg check.
+	CommitMsg CommitMsgConfig `yaml:"commit_msg"`
+
+	// Ticket configures how the ticket of a branch is added to commit messages by the prepare-commit-msg hook.
+	Ticket TicketConfig `yaml:"ticket"`
+
+	// Profiles are the named profiles of checks, which may be selected by `--check-profile`.
+	// The pre-commit hook uses the `fast` profile, and the pre-push hook uses the `full` profile.
+	Profiles map[string]ProfileConfig `yaml:"profiles"`
+
+	// Tools pins the version of each tool installed by goprecommit, by name:
+	// goimports, golangci-lint, staticcheck.
+	// Tools may also be pinned by `tool` directives in go.mod.
+	Tools map[string]string `yaml:"tools"`
+
+	generatedCodeMarker *regexp.Regexp
+	modTools            map[string]string
+}
+
+// DefaultConfig returns the configuration used when there is no configuration file.
+func DefaultConfig() *Config {
+	return &Config{
+		ProtectedBranches:   []string{"production", "staging"},
+		Vendor:              []string{"vendor"},
+		GeneratedCodeMarker: `^// Code generated by .* DO NOT EDIT\.$`,
+		EOLSkip:             []string{".jar"},
+
+		CommitMsg: CommitMsgConfig{
+			Types:         []string{"build", "chore", "ci", "docs", "feat", "fix", "perf", "refactor", "revert", "style", "test"},
+			SubjectLength: 72,
+			BodyWidth:     72,
+		},
+
+		Ticket: TicketConfig{
+			Position: TicketTrailer,
+			Trailer:  "Refs",
+		},
+
+		Profiles: map[string]ProfileConfig{
+			"fast": {
+				Skip:  []string{"tidy", "lint", "golangci-lint", "staticcheck"},
+				Short: true,
+			},
+			"full": {
+				AllTests: true,
+				Race:     true,
+				Cover:    true,
+			},
+		},
+	}
+}
+
+// LoadConfig reads the ConfigFilename in the given directory, and layers it on top of the receiver.
+// Any relevant flags set on the command-line are then applied on top of that.
+//
+// Errors name the file in the working tree, even when it is read from a snapshot.
+func (c *Config) LoadConfig(dir string) (*Config, error) {
+	filename := filepath.Join(dir, ConfigFilename)
+	name := workTreePath(filename)
+
+	var layer Config
+
+	data, err := os.ReadFile(filename)
+	switch {
+	case err == nil:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+
+		if err := dec.Decode(&layer); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if tool := layer.Vet.Vettool; tool != "" && !filepath.IsAbs(tool) {
+			abs, err := filepath.Abs(filepath.Join(dir, tool))
+			if err != nil {
+				return nil, fmt.Errorf("%s: vettool: %w", name, err)
+			}
+
+			layer.Vet.Vettool = abs
+		}
+
+		Verbose("read config", name)
+
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	// the tools of a module are pinned only by its own go.mod.
+	goMod := filepath.Join(dir, "go.mod")
+	if testCanRead(goMod) {
+		tools, err := modTools(goMod)
+		if err != nil {
+			return nil, err
+		}
+
+		if tools == nil {
+			tools = make(map[string]string)
+		}
+
+		layer.modTools = tools
+	}
+
+	merged := c.merge(&layer)
+	merged.applyFlags()
+
+	if _, err := merged.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if err := merged.checkBranchPolicy(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if err := merged.checkAnalyzers(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if err := merged.checkChecks(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if name := Flags.CheckProfile; name != "" {
+		if _, ok := merged.Profiles[name]; !ok {
+			return nil, fmt.Errorf("unknown check profile: %s", name)
+		}
+	}
+
+	return merged, nil
+}
+
+// merge returns a new Config where any values set in `layer` replace those of the receiver.
+func (c *Config) merge(layer *Config) *Config {
+	merged := *c
+
+	if layer.ProtectedBranches != nil {
+		merged.ProtectedBranches = layer.ProtectedBranches
+	}
+
+	if layer.ProtectedRemotes != nil {
+		merged.ProtectedRemotes = layer.ProtectedRemotes
+	}
+
+	if layer.BranchName != "" {
+		merged.BranchName = layer.BranchName
+	}
+
+	if layer.Vendor != nil {
+		merged.Vendor = layer.Vendor
+	}
+
+	if layer.GeneratedCodeMarker != "" {
+		merged.GeneratedCodeMarker = layer.GeneratedCodeMarker
+		merged.generatedCodeMarker = nil
+	}
+
+	if layer.EOLSkip != nil {
+		merged.EOLSkip = layer.EOLSkip
+	}
+
+	merged.Checks = make(map[string]CheckConfig)
+	for name, check := range c.Checks {
+		merged.Checks[name] = check
+	}
+
+	for name, check := range layer.Checks {
+		prev := merged.Checks[name]
+
+		if check.Enabled != nil {
+			prev.Enabled = check.Enabled
+		}
+
+		if check.Severity != 0 {
+			prev.Severity = check.Severity
+		}
+
+		merged.Checks[name] = prev
+	}
+
+	merged.Vet.Analyzers = make(map[string]bool)
+	for name, enabled := range c.Vet.Analyzers {
+		merged.Vet.Analyzers[name] = enabled
+	}
+
+	for name, enabled := range layer.Vet.Analyzers {
+		merged.Vet.Analyzers[name] = enabled
+	}
+
+	if layer.Vet.Vettool != "" {
+		merged.Vet.Vettool = layer.Vet.Vettool
+	}
+
+	if layer.CommitMsg.Types != nil {
+		merged.CommitMsg.Types = layer.CommitMsg.Types
+	}
+
+	if layer.CommitMsg.Scopes != nil {
+		merged.CommitMsg.Scopes = layer.CommitMsg.Scopes
+	}
+
+	if layer.CommitMsg.SubjectLength != 0 {
+		merged.CommitMsg.SubjectLength = layer.CommitMsg.SubjectLength
+	}
+
+	if layer.CommitMsg.BodyWidth != 0 {
+		merged.CommitMsg.BodyWidth = layer.CommitMsg.BodyWidth
+	}
+
+	if layer.CommitMsg.Trailers != nil {
+		merged.CommitMsg.Trailers = layer.CommitMsg.Trailers
+	}
+
+	if layer.Ticket.Pattern != "" {
+		merged.Ticket.Pattern = layer.Ticket.Pattern
+	}
+
+	if layer.Ticket.Position != "" {
+		merged.Ticket.Position = layer.Ticket.Position
+	}
+
+	if layer.Ticket.Trailer != "" {
+		merged.Ticket.Trailer = layer.Ticket.Trailer
+	}
+
+	merged.Profiles = make(map[string]ProfileConfig)
+	for name, profile := range c.Profiles {
+		merged.Profiles[name] = profile
+	}
+
+	for name, profile := range layer.Profiles {
+		merged.Profiles[name] = profile
+	}
+
+	merged.Tools = make(map[string]string)
+	for name, version := range c.Tools {
+		merged.Tools[name] = version
+	}
+
+	for name, version := range layer.Tools {
+		merged.Tools[name] = version
+	}
+
+	if layer.modTools != nil {
+		merged.modTools = layer.modTools
+	}
+
+	return &merged
+}
+
+func (c *Config) compile() (*regexp.Regexp, error) {
+	if c.generatedCodeMarker != nil {
+		return c.generatedCodeMarker, nil
+	}
+
+	re, err := regexp.Compile(c.GeneratedCodeMarker)
+	if err != nil {
+		return nil, fmt.Errorf("generated_code_marker: %w", err)
+	}
+
+	c.generatedCodeMarker = re
+
+	return re, nil
+}
+
+// IsGenerated returns true if the given file contains the generated code marker.
+func (c *Config) IsGenerated(filename string) bool {
+	re, err := c.compile()
+	if err != nil {
+		return false
+	}
+
+	return testFileContains(filename, re)
+}
+
+// matchBranch returns true if the given branch name matches the pattern,
+// which is a regular expression if it is enclosed in slashes, or otherwise a glob.
+func matchBranch(pattern, branch string) (bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return false, err
+		}
+
+		return re.MatchString(branch), nil
+	}
+
+	return path.Match(pattern, branch)
+}
+
+// checkBranchPolicy returns an error if any of the ProtectedBranches, the BranchName, or the Ticket, is not valid.
+func (c *Config) checkBranchPolicy() error {
+	for _, pattern := range c.ProtectedBranches {
+		if _, err := matchBranch(pattern, ""); err != nil {
+			return fmt.Errorf("protected_branches: %s: %w", pattern, err)
+		}
+	}
+
+	if c.BranchName != "" {
+		if _, err := regexp.Compile(c.BranchName); err != nil {
+			return fmt.Errorf("branch_name: %w", err)
+		}
+	}
+
+	if _, err := regexp.Compile(c.Ticket.Pattern); err != nil {
+		return fmt.Errorf("ticket: pattern: %w", err)
+	}
+
+	switch c.Ticket.Position {
+	case TicketPrefix, TicketTrailer:
+	default:
+		return fmt.Errorf("ticket: unknown position: %s", c.Ticket.Position)
+	}
+
+	return nil
+}
+
+// checkChecks returns an error if any of the Checks is not the name of a registered Checker.
+func (c *Config) checkChecks() error {
+	var unknown []string
+	for name := range c.Checks {
+		if !hasChecker(name) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("checks: unknown checks: %s", strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+// checkAnalyzers returns an error if any of the vet Analyzers is not one that `go vet` runs,
+// unless there is a Vettool, which may run analyzers of its own.
+func (c *Config) checkAnalyzers() error {
+	if c.Vet.Vettool != "" {
+		return nil
+	}
+
+	var unknown []string
+	for name := range c.Vet.Analyzers {
+		if !hasAnalyzer(vetAnalyzers, name) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("vet: analyzers: unknown analyzers: %s", strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+// IsProtected returns the first of the ProtectedBranches that matches the given branch name, and true,
+// or false if none match.
+func (c *Config) IsProtected(branch string) (string, bool) {
+	for _, pattern := range c.ProtectedBranches {
+		if ok, _ := matchBranch(pattern, branch); ok {
+			return pattern, true
+		}
+	}
+
+	return "", false
+}
+
+// ValidBranchName returns true if the given branch name matches the BranchName, or if it is not set.
+func (c *Config) ValidBranchName(branch string) bool {
+	if c.BranchName == "" {
+		return true
+	}
+
+	re, err := regexp.Compile(c.BranchName)
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(branch)
+}
+
+// IsVendored returns true if any element of the given path is a vendor directory.
+func (c *Config) IsVendored(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		for _, vendor := range c.Vendor {
+			if elem == vendor {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// SkipEOL returns true if the given file should not be checked for ending with an EOL.
+func (c *Config) SkipEOL(filename string) bool {
+	for _, suffix := range c.EOLSkip {
+		if strings.HasSuffix(filename, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Enabled returns true if the named check is enabled, and is not skipped by the selected Profile.
+func (c *Config) Enabled(check string) bool {
+	for _, skip := range c.Profile().Skip {
+		if skip == check {
+			return false
+		}
+	}
+
+	enabled := c.Checks[check].Enabled
+	return enabled == nil || *enabled
+}
+
+// Profile returns the profile of checks selected by `--check-profile`, or by the hook being run.
+// If no profile is selected, then the zero value is returned, which runs every enabled check.
+func (c *Config) Profile() ProfileConfig {
+	return c.Profiles[Flags.CheckProfile]
+}
+
+// Severity returns the severity of the issues found by the named check.
+func (c *Config) Severity(check string) Severity {
+	if sev := c.Checks[check].Severity; sev != 0 {
+		return sev
+	}
+
+	return SeverityError
+}
+
+// applyFlags overrides the configuration with any relevant flags set on the command-line.
+func (c *Config) applyFlags() {
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "lint", "nolint":
+			c.setEnabled("lint", Flags.Lint)
+		case "vet", "novet":
+			c.setEnabled("vet", Flags.Vet)
+		}
+	})
+}
+
+func (c *Config) setEnabled(check string, enabled bool) {
+	if c.Checks == nil {
+		c.Checks = make(map[string]CheckConfig)
+	}
+
+	cfg := c.Checks[check]
+	cfg.Enabled = &enabled
+	c.Checks[check] = cfg
+}
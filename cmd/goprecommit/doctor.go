@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	flag "github.com/puellanivis/breton/lib/gnuflag"
+)
+
+// minGitVersion is the oldest version of git that goprecommit works with,
+// being the first to support `git rev-parse --absolute-git-dir`.
+var minGitVersion = SemVer{Major: 2, Minor: 13}
+
+// doctor collects the problems found in the environment that goprecommit runs in.
+type doctor struct {
+	problems int
+
+	// tools are the tools already checked, so that tools shared by modules are checked only once.
+	tools map[string]bool
+}
+
+// problem prints a problem, and how it can be fixed.
+func (d *doctor) problem(context, msg, fix string) {
+	d.problems++
+
+	Error(context, msg)
+	Info(context, "suggested fix: ", fix)
+}
+
+// checkGit checks that git can be found, and is recent enough.
+func (d *doctor) checkGit(ctx context.Context) bool {
+	path, err := findBin(gitCmd.Bin)
+	if err != nil {
+		d.problem("git", "not found in PATH", "install git, or set $GIT to the path of git")
+		return false
+	}
+
+	ver, err := gitCmd.Version(ctx)
+	switch {
+	case err != nil:
+		d.problem("git", err.Error(), "reinstall git, or set $GIT to the path of git")
+		return false
+
+	case ver.olderThan(minGitVersion):
+		d.problem("git", fmt.Sprint(ver, " is too old"), fmt.Sprint("upgrade git to ", &minGitVersion, " or later"))
+
+	default:
+		OK("git", ver, ": ", path)
+	}
+
+	return true
+}
+
+// checkGo checks that go can be found, is recent enough, and installs binaries into a directory in the PATH.
+func (d *doctor) checkGo(ctx context.Context) bool {
+	path, err := findBin(goCmd.Bin)
+	if err != nil {
+		d.problem("go", "not found in PATH", "install go, or set $GO to the path of go")
+		return false
+	}
+
+	// GoBin.Version exits if it cannot find the version, which is itself the problem.
+	if _, ok := goCmd.CombinedOutput(ctx, "version"); !ok {
+		d.problem("go", "could not get go version", "reinstall go, or set $GO to the path of go")
+		return false
+	}
+
+	ver := goCmd.Version(ctx)
+	if ver.olderThan(minGoVersion) {
+		d.problem("go", fmt.Sprint(ver, " is too old"), fmt.Sprint("upgrade go to ", &minGoVersion, " or later"))
+	} else {
+		OK("go", ver, ": ", path)
+	}
+
+	if dir := gopathBin(); !inPath(dir) {
+		d.problem("go", dir+" is not in PATH, so tools that go installs cannot be found",
+			"add "+dir+" to PATH in your shell profile")
+	}
+
+	return true
+}
+
+// checkTool checks that the command of the tool from `pkg` can be made available to the module in `dir`,
+// as decided by resolveTool, but without installing it.
+func (d *doctor) checkTool(ctx context.Context, cfg *Config, dir string, c *command, pkg, defaultVersion string) {
+	r, err := resolveTool(ctx, cfg, c.Bin, pkg, defaultVersion)
+	if err != nil {
+		d.problem(c.Bin, "tools cache: "+err.Error(), "set $HOME, or $XDG_CACHE_HOME")
+		return
+	}
+
+	if r.Source == toolGoTool {
+		OK(c.Bin, "go tool ", pkg, " in ", dir)
+		return
+	}
+
+	if d.seen(c.Bin, r.Version) {
+		return
+	}
+
+	if r.Source != toolPinned && r.Version != "" {
+		d.problem(c.Bin, "cannot install pinned version "+r.Version, fmt.Sprint("upgrade go to ", &minGoVersion, " or later"))
+		return
+	}
+
+	switch r.Source {
+	case toolPinned:
+		if _, err := findBin(r.Path); err != nil {
+			d.problem(c.Bin, r.Version+" is not installed, and needs the network to be installed",
+				"GOBIN="+shellQuote(filepath.Dir(r.Path))+" go install "+pkg+"@"+r.Version)
+			return
+		}
+
+		OK(c.Bin, r.Version, ": ", r.Path)
+
+	case toolInPath:
+		if ver, ok := goCmd.BinaryVersion(ctx, r.Path); ok {
+			OK(c.Bin, ver, ": ", r.Path)
+		} else {
+			OK(c.Bin, r.Path)
+		}
+
+	case toolLatest:
+		d.problem(c.Bin, "not found in PATH", "go install "+pkg+"@latest")
+	}
+}
+
+// seen returns true if the given key has already been seen, and marks it as seen.
+func (d *doctor) seen(key ...string) bool {
+	k := strings.Join(key, "\x00")
+
+	if d.tools[k] {
+		return true
+	}
+
+	if d.tools == nil {
+		d.tools = make(map[string]bool)
+	}
+	d.tools[k] = true
+
+	return false
+}
+
+// checkModule checks the tools that the checks need for the module with the given go.mod.
+func (d *doctor) checkModule(ctx context.Context, cfg *Config, goMod string) {
+	dir := filepath.Dir(goMod)
+
+	if dir != "." {
+		var err error
+
+		cfg, err = cfg.LoadConfig(dir)
+		if err != nil {
+			d.problem("config", err.Error(), "fix the configuration file")
+			return
+		}
+	}
+
+	if cfg.Enabled("goimports") {
+		d.checkTool(ctx, cfg, dir, &goimportsCmd.command, goimportsPkg, goimportsVersion)
+	}
+
+	top := gitCmd.TopLevel(ctx)
+
+	if config, ok := findConfig(dir, top, golangciLintConfigs); ok && cfg.Enabled("golangci-lint") {
+		major := golangciLintMajor(config)
+
+		d.checkTool(ctx, cfg, dir, &golangciLintCmd.command, golangciLintPkg(major), golangciLintVersions[major])
+	}
+
+	if _, ok := findConfig(dir, top, staticcheckConfigs); ok && cfg.Enabled("staticcheck") {
+		d.checkTool(ctx, cfg, dir, &staticcheckCmd.command, "honnef.co/go/tools/cmd/staticcheck", staticcheckVersion)
+	}
+}
+
+// checkHeadBranches checks that the head branches of the protected remotes can be found, for the branch check.
+func (d *doctor) checkHeadBranches(ctx context.Context, cfg *Config) {
+	remotes := cfg.ProtectedRemotes
+	if remotes == nil {
+		remotes = gitCmd.Remotes(ctx)
+	}
+
+	if len(remotes) == 0 {
+		OK("branch", "no remotes, so ", gitCmd.DefaultBranch(ctx), " is protected")
+		return
+	}
+
+	for _, remote := range remotes {
+		head, ok := gitCmd.RemoteHead(ctx, remote)
+		if !ok {
+			d.problem(remote+"/HEAD", "the head branch of "+remote+" is not known, so it is not protected",
+				"git remote set-head "+remote+" --auto")
+			continue
+		}
+
+		OK(remote+"/HEAD", head)
+	}
+}
+
+var hookBinRegexp = regexp.MustCompile(`exec '((?:[^']|'\\'')*)'`)
+
+// checkHook checks that the named hook is installed, and that it runs goprecommit.
+func (d *doctor) checkHook(dir, name string) {
+	filename := filepath.Join(dir, name)
+
+	fi, err := os.Stat(filename)
+	if err != nil {
+		d.problem(name, "hook is not installed", "goprecommit install --hooks="+name)
+		return
+	}
+
+	if fi.Mode()&0111 == 0 {
+		d.problem(name, filename+" is not executable, so git does not run it", "chmod +x "+shellQuote(filename))
+		return
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		d.problem(name, err.Error(), "goprecommit install --hooks="+name)
+		return
+	}
+
+	if !isGoprecommitHook(filename) {
+		if !strings.Contains(string(data), "goprecommit") {
+			d.problem(name, filename+" does not run goprecommit",
+				"goprecommit install --hooks="+name+", which keeps the existing hook, and runs it first")
+			return
+		}
+
+		// such as the hook from the git-template, which finds goprecommit in the PATH.
+		if _, err := findBin("goprecommit"); err != nil {
+			d.problem(name, filename+" runs goprecommit from the PATH, but it is not in PATH",
+				"add the directory of goprecommit to PATH, or goprecommit install --hooks="+name)
+			return
+		}
+
+		OK(name, "installed in ", dir)
+		return
+	}
+
+	m := hookBinRegexp.FindSubmatch(data)
+	if m == nil {
+		d.problem(name, filename+" is damaged", "goprecommit install --hooks="+name)
+		return
+	}
+
+	bin := strings.ReplaceAll(string(m[1]), `'\''`, "'")
+	if _, err := findBin(bin); err != nil {
+		d.problem(name, "hook runs "+bin+", which is not executable", "goprecommit install --hooks="+name)
+		return
+	}
+
+	OK(name, "installed in ", dir)
+}
+
+// doctorCommand runs the `doctor` command, which checks the environment that goprecommit runs in,
+// and lists every problem found with a suggested fix, and then exits.
+func doctorCommand(ctx context.Context, args []string) {
+	if err := flag.CommandLine.Parse(args); err != nil {
+		Error("doctor", err)
+		Exit(2)
+	}
+
+	if flag.NArg() > 0 {
+		Error("doctor", "unexpected arguments: ", strings.Join(flag.Args(), " "))
+		Exit(2)
+	}
+
+	names, err := hookNames()
+	if err != nil {
+		Error("doctor", err)
+		Exit(2)
+	}
+
+	var d doctor
+
+	gitOK := d.checkGit(ctx)
+	goOK := d.checkGo(ctx)
+
+	switch {
+	case !gitOK:
+	case !gitCmd.InRepo(ctx):
+		Notice("doctor", "not in git repo, so only git and go were checked")
+
+	default:
+		cfg, err := DefaultConfig().LoadConfig(gitCmd.TopLevel(ctx))
+		if err != nil {
+			d.problem("config", err.Error(), "fix the configuration file")
+			cfg = DefaultConfig()
+		}
+
+		if goOK {
+			var goMods []string
+			for _, file := range gitCmd.Files(ctx) {
+				if filepath.Base(file) == "go.mod" && !cfg.IsVendored(file) {
+					goMods = append(goMods, file)
+				}
+			}
+
+			if len(goMods) == 0 {
+				goMods = append(goMods, "go.mod")
+			}
+
+			for _, goMod := range goMods {
+				d.checkModule(ctx, cfg, goMod)
+			}
+		}
+
+		if cfg.Enabled("branch") {
+			d.checkHeadBranches(ctx, cfg)
+		}
+
+		dir := gitCmd.HooksDir(ctx)
+		for _, name := range names {
+			d.checkHook(dir, name)
+		}
+	}
+
+	if d.problems > 0 {
+		Error("doctor", "problems found: ", d.problems)
+		Exit(1)
+	}
+
+	OK("doctor", "no problems found")
+	Exit(0)
+}
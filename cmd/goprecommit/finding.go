@@ -0,0 +1,101 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Finding is a single issue found by a Checker.
+type Finding struct {
+	// Check is the name of the Checker that found the issue.
+	Check string `json:"check"`
+
+	// Rule identifies what kind of issue this is, it is unique only within the Check.
+	Rule string `json:"rule,omitempty"`
+
+	Severity Severity `json:"severity"`
+
+	// File, Line, and Column locate the issue, each is optional, and a zero value means unknown.
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+
+	Message string `json:"message"`
+
+	// Fix is an optional suggested fix for the issue.
+	Fix *Fix `json:"fix,omitempty"`
+}
+
+// Fix is a suggested fix for a Finding.
+type Fix struct {
+	// Description describes the fix in a human readable form.
+	Description string `json:"description"`
+
+	// Diff is an optional unified diff that would fix the issue.
+	Diff string `json:"diff,omitempty"`
+}
+
+// Blocks returns true if the finding should block the commit.
+func (f Finding) Blocks() bool {
+	return f.Severity >= SeverityError
+}
+
+// Position returns the "file:line:column" of the finding,
+// leaving off any trailing parts that are unknown.
+func (f Finding) Position() string {
+	if f.File == "" {
+		return ""
+	}
+
+	pos := f.File
+
+	if f.Line > 0 {
+		pos += ":" + strconv.Itoa(f.Line)
+
+		if f.Column > 0 {
+			pos += ":" + strconv.Itoa(f.Column)
+		}
+	}
+
+	return pos
+}
+
+func (f Finding) String() string {
+	pos := f.Position()
+
+	switch {
+	case pos == "":
+		return f.Message
+	case f.Message == "":
+		return pos
+	}
+
+	return pos + ": " + f.Message
+}
+
+var positionRegexp = regexp.MustCompile(`^([^:\s][^:]*):(\d+)(?::(\d+))?: (.*)$`)
+
+// parsePosition parses a line of the common form "file:line:column: message", where the column is optional,
+// into a Finding with the given rule.
+// If the line does not have this form, the whole line is used as the message.
+func parsePosition(rule, line string) Finding {
+	m := positionRegexp.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Finding{
+			Rule:    rule,
+			Message: line,
+		}
+	}
+
+	lineno, _ := strconv.Atoi(m[2])
+	column, _ := strconv.Atoi(m[3])
+
+	return Finding{
+		Rule:    rule,
+		File:    m[1],
+		Line:    lineno,
+		Column:  column,
+		Message: m[4],
+	}
+}
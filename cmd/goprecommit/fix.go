@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// Fixer may be implemented by a Checker that is able to fix the issues that it finds.
+//
+// Fix is only called with `--fix`, and is always called after Run, even if there were no findings.
+// It should fix the files in the working tree, not the snapshot, and re-stage them.
+// It returns any findings that could not be fixed.
+type Fixer interface {
+	Fix(ctx context.Context, t *Target, findings []Finding) []Finding
+}
+
+// canRestage returns true if the given files in the working tree can be fixed and re-staged,
+// that is, none of them have changes that are not already staged.
+// Otherwise, it returns false, and the reason why.
+func canRestage(ctx context.Context, files ...string) (bool, string) {
+	for _, file := range files {
+		if gitCmd.HasUnstagedChanges(ctx, file) {
+			return false, "not fixed, it is only partially staged"
+		}
+	}
+
+	return true, ""
+}
+
+// restage stages the given files from the working tree, and reports them as fixed.
+func restage(ctx context.Context, check string, files ...string) bool {
+	if output, ok := gitCmd.Add(ctx, files...); !ok {
+		Error(check, "could not re-stage fixed files: ", output)
+		return false
+	}
+
+	top := gitCmd.TopLevel(ctx)
+
+	for _, file := range files {
+		if rel, err := filepath.Rel(top, file); err == nil {
+			file = rel
+		}
+
+		OK(check, "fixed and re-staged ", file)
+	}
+
+	return true
+}
+
+// fixFormat rewrites each of the files from the given findings in the working tree with the given formatter,
+// and then re-stages them, unless they are partially staged.
+func fixFormat(ctx context.Context, t *Target, check string, formatter *GofmtBin, findings []Finding) []Finding {
+	var unfixed []Finding
+	var files []string
+
+	for _, f := range findings {
+		file := filepath.Join(t.WorkTree, f.File)
+
+		if ok, reason := canRestage(ctx, file); !ok {
+			f.Message += ": " + reason
+			unfixed = append(unfixed, f)
+			continue
+		}
+
+		files = append(files, file)
+	}
+
+	if len(files) == 0 {
+		return unfixed
+	}
+
+	if output, ok := formatter.Write(ctx, files); !ok {
+		Error(check, output)
+		return findings
+	}
+
+	if !restage(ctx, check, files...) {
+		return findings
+	}
+
+	return unfixed
+}
+
+func (gofmtChecker) Fix(ctx context.Context, t *Target, findings []Finding) []Finding {
+	return fixFormat(ctx, t, "gofmt", &gofmtCmd, findings)
+}
+
+func (goimportsChecker) Fix(ctx context.Context, t *Target, findings []Finding) []Finding {
+	return fixFormat(ctx, t, "goimports", &goimportsCmd, findings)
+}
+
+// Fix runs `go mod tidy` in the working tree, and then re-stages go.mod and go.sum if they changed.
+// It only fixes a module where go.mod or go.sum are not tidy.
+func (tidyChecker) Fix(ctx context.Context, t *Target, findings []Finding) []Finding {
+	var untidy bool
+	for _, f := range findings {
+		switch f.Rule {
+		case "failed":
+			return findings
+		case "tidy":
+			untidy = true
+		}
+	}
+
+	if !untidy {
+		return findings
+	}
+
+	var files []string
+	for _, name := range []string{"go.mod", "go.sum"} {
+		file := filepath.Join(t.WorkTree, name)
+
+		if testCanRead(file) {
+			files = append(files, file)
+		}
+	}
+
+	if ok, reason := canRestage(ctx, files...); !ok {
+		return []Finding{{
+			Rule:    "tidy",
+			File:    "go.mod",
+			Message: "not tidy, and " + reason,
+		}}
+	}
+
+	saveDir, err := os.Getwd()
+	if err != nil {
+		Error("tidy", "getwd:", err)
+		return findings
+	}
+
+	if err := os.Chdir(t.WorkTree); err != nil {
+		Error("tidy", "chdir:", err)
+		return findings
+	}
+	defer func() {
+		if err := os.Chdir(saveDir); err != nil {
+			Error("tidy", "popdir:", err)
+		}
+	}()
+
+	if !goCmd.ModTidy(ctx) {
+		return []Finding{{
+			Rule:    "failed",
+			File:    "go.mod",
+			Message: "go mod tidy failed in the working tree",
+		}}
+	}
+
+	// go.sum may have only just been created.
+	files = files[:0]
+	for _, name := range []string{"go.mod", "go.sum"} {
+		file := filepath.Join(t.WorkTree, name)
+
+		if testCanRead(file) && gitCmd.HasUnstagedChanges(ctx, file) {
+			files = append(files, file)
+		}
+	}
+
+	if len(files) > 0 && !restage(ctx, "tidy", files...) {
+		return findings
+	}
+
+	return nil
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 )
@@ -12,11 +16,20 @@ import (
 type GitBin struct {
 	command
 
-	mu    sync.Mutex
-	files map[string][]string
+	mu      sync.Mutex
+	files   map[string][]string
+	staged  map[string][]string
+	deleted map[string][]string
+	base    string
 
-	branch once[string]
-	head   once[string]
+	// env are the variables set for every git command, such as to point git at a snapshot,
+	// which are never set in the environment of the process, so that they cannot leak into other commands.
+	env []string
+
+	branch        once[string]
+	defaultBranch once[string]
+	top           once[string]
+	gitDir        once[string]
 }
 
 var gitCmd = GitBin{
@@ -25,6 +38,23 @@ var gitCmd = GitBin{
 	},
 }
 
+// SetEnv sets the given `KEY=value` variables in the environment of every git command run after it,
+// replacing any set before with the same key.
+func (git *GitBin) SetEnv(vars ...string) {
+	for _, v := range vars {
+		key, _, _ := strings.Cut(v, "=")
+
+		git.env = slices.DeleteFunc(git.env, func(prev string) bool {
+			return strings.HasPrefix(prev, key+"=")
+		})
+		git.env = append(git.env, v)
+	}
+
+	git.environ = func(env []string) []string {
+		return append(env, git.env...)
+	}
+}
+
 // InRepo returns true if the current working directory is inside the git work tree.
 func (git *GitBin) InRepo(ctx context.Context) bool {
 	out, ok := git.CombinedOutput(ctx, "rev-parse", "--is-inside-work-tree")
@@ -37,23 +67,262 @@ func (git *GitBin) InRepo(ctx context.Context) bool {
 	return out == "true"
 }
 
-// Branch returns the name of the current branch.
-func (git *GitBin) Branch(ctx context.Context) string {
-	return git.branch.Get(func() string {
-		return git.MustOutput(ctx, "rev-parse", "--abbrev-ref", "HEAD")
+var gitVersionRegexp = regexp.MustCompile(`\d+(\.\d+){0,2}`)
+
+// Version returns the parsed SemVer of the binary.
+// Unlike GoBin.Version, it does not exit if the version cannot be found.
+func (git *GitBin) Version(ctx context.Context) (*SemVer, error) {
+	output, ok := git.CombinedOutput(ctx, "--version")
+	if !ok {
+		return nil, fmt.Errorf("could not get git version: %s", output)
+	}
+
+	ver := gitVersionRegexp.FindString(strings.TrimPrefix(output, "git version "))
+	if ver == "" {
+		return nil, fmt.Errorf("could not find version: %s", output)
+	}
+
+	return ParseVersion(ver)
+}
+
+// Branch returns the name of the current branch, which may not have any commits yet.
+// It returns false if HEAD is detached.
+func (git *GitBin) Branch(ctx context.Context) (string, bool) {
+	branch := git.branch.Get(func() string {
+		branch, ok := git.CombinedOutput(ctx, "symbolic-ref", "--quiet", "--short", "HEAD")
+		if !ok {
+			return ""
+		}
+
+		return branch
+	})
+
+	return branch, branch != ""
+}
+
+// HeadBranches returns the names of the head branches of the given remotes, or of every remote if none are given.
+// Remotes without a known head branch are skipped.
+//
+// If none of the remotes has a known head branch, such as when there are no remotes,
+// then it returns the DefaultBranch instead.
+func (git *GitBin) HeadBranches(ctx context.Context, remotes []string) []string {
+	if remotes == nil {
+		remotes = git.Remotes(ctx)
+	}
+
+	var heads []string
+	for _, remote := range remotes {
+		head, ok := git.RemoteHead(ctx, remote)
+		if !ok {
+			Verbose(remote, "no head branch known")
+			continue
+		}
+
+		heads = append(heads, head)
+	}
+
+	if len(heads) == 0 {
+		return []string{git.DefaultBranch(ctx)}
+	}
+
+	return heads
+}
+
+// DefaultBranch returns the name of the branch that `git init` creates, from `init.defaultBranch`.
+func (git *GitBin) DefaultBranch(ctx context.Context) string {
+	return git.defaultBranch.Get(func() string {
+		branch, ok := git.CombinedOutput(ctx, "config", "--get", "init.defaultBranch")
+		if !ok || branch == "" {
+			return "master"
+		}
+
+		return branch
 	})
 }
 
-// HeadBranch returns the name of the head branch.
-func (git *GitBin) HeadBranch(ctx context.Context) string {
-	return git.head.Get(func() string {
-		head := git.MustOutput(ctx, "rev-parse", "--abbrev-ref", "refs/remotes/origin/HEAD")
-		return strings.TrimPrefix(head, "origin/")
+// Remotes returns the names of all of the remotes.
+func (git *GitBin) Remotes(ctx context.Context) []string {
+	return strings.Fields(git.MustOutput(ctx, "remote"))
+}
+
+// RemoteHead returns the name of the head branch of the given remote.
+// It returns false if the remote has no head branch, such as when it was not set by `git clone`.
+func (git *GitBin) RemoteHead(ctx context.Context, remote string) (string, bool) {
+	head, ok := git.CombinedOutput(ctx, "rev-parse", "--abbrev-ref", "refs/remotes/"+remote+"/HEAD")
+	if !ok {
+		return "", false
+	}
+
+	return strings.TrimPrefix(head, remote+"/"), true
+}
+
+// CommentChar returns the string that starts a comment line in a commit message, from `core.commentChar`.
+func (git *GitBin) CommentChar(ctx context.Context) string {
+	char, ok := git.CombinedOutput(ctx, "config", "--get", "core.commentChar")
+	if !ok || char == "" || char == "auto" {
+		return "#"
+	}
+
+	return char
+}
+
+// TopLevel returns the absolute path of the top-level directory of the working tree.
+func (git *GitBin) TopLevel(ctx context.Context) string {
+	return git.top.Get(func() string {
+		return git.MustOutput(ctx, "rev-parse", "--show-toplevel")
 	})
 }
 
+// GitDir returns the absolute path of the git directory.
+func (git *GitBin) GitDir(ctx context.Context) string {
+	return git.gitDir.Get(func() string {
+		return git.MustOutput(ctx, "rev-parse", "--absolute-git-dir")
+	})
+}
+
+// HooksDir returns the absolute path of the directory that git runs hooks from,
+// which honours `core.hooksPath`, and is shared by all worktrees.
+func (git *GitBin) HooksDir(ctx context.Context) string {
+	return git.GitPath(ctx, "hooks")
+}
+
+// GitPath returns the absolute path of the given path inside the git directory,
+// which is specific to the current worktree, unless git shares the path between all worktrees.
+func (git *GitBin) GitPath(ctx context.Context, path string) string {
+	dir := git.MustOutput(ctx, "rev-parse", "--git-path", path)
+
+	if !filepath.IsAbs(dir) {
+		dir = mustAbs(dir)
+	}
+
+	return dir
+}
+
+// CheckoutIndex copies every file in the index into the given directory,
+// as if it were the top-level directory of the working tree.
+// Files already in the directory are overwritten.
+func (git *GitBin) CheckoutIndex(ctx context.Context, dir string) (string, bool) {
+	cmd := git.Command(ctx, "checkout-index", "--all", "--force", "--prefix="+dir+string(os.PathSeparator))
+	cmd.Dir = git.TopLevel(ctx)
+
+	return git.handleOutput(cmd.CombinedOutput())
+}
+
+// IndexFiles returns every file in the index, relative to the top-level directory of the working tree.
+func (git *GitBin) IndexFiles(ctx context.Context) ([]string, bool) {
+	cmd := git.Command(ctx, "ls-files", "-z", "--full-name")
+	cmd.Dir = git.TopLevel(ctx)
+
+	output, ok := git.handleOutput(cmd.Output())
+	if !ok {
+		return nil, false
+	}
+
+	var files []string
+	for _, file := range strings.Split(output, "\x00") {
+		if file != "" {
+			files = append(files, filepath.FromSlash(file))
+		}
+	}
+
+	return files, true
+}
+
+// ReadTree reads the tree of the given commit into the index.
+func (git *GitBin) ReadTree(ctx context.Context, rev string) (string, bool) {
+	return git.CombinedOutput(ctx, "read-tree", rev)
+}
+
+// MergeBase returns the best common ancestor of the two given commits.
+// It returns false if there is none, such as when either commit is not known.
+func (git *GitBin) MergeBase(ctx context.Context, a, b string) (string, bool) {
+	return git.CombinedOutput(ctx, "merge-base", a, b)
+}
+
+// workTreeCommand returns a command that operates on the actual working tree, and its actual index,
+// even while checks are being run against a snapshot of the index, or of a commit.
+func (git *GitBin) workTreeCommand(ctx context.Context, args ...string) *exec.Cmd {
+	top := git.TopLevel(ctx)
+
+	cmd := git.Command(ctx, args...)
+	cmd.Dir = top
+	cmd.Env = append(os.Environ(), "GIT_WORK_TREE="+top)
+
+	return cmd
+}
+
+// HasUnstagedChanges returns true if the given file in the working tree differs from the index.
+func (git *GitBin) HasUnstagedChanges(ctx context.Context, filename string) bool {
+	cmd := git.workTreeCommand(ctx, "diff", "--quiet", "--", filename)
+
+	_, ok := git.handleOutput(cmd.CombinedOutput())
+	return !ok
+}
+
+// Add stages the given files from the working tree.
+func (git *GitBin) Add(ctx context.Context, filenames ...string) (string, bool) {
+	cmd := git.workTreeCommand(ctx, append([]string{"add", "--"}, filenames...)...)
+
+	return git.handleOutput(cmd.CombinedOutput())
+}
+
+// DiffFiles returns a unified diff between the two given files, which are relative to the given dir.
+// The files need not be in a repo.
+func (git *GitBin) DiffFiles(ctx context.Context, dir, a, b string) string {
+	cmd := git.Command(ctx, "diff", "--no-index", "--no-color", "--no-prefix", "--", a, b)
+	cmd.Dir = dir
+
+	// `git diff --no-index` exits with a failure when the files differ.
+	output, _ := git.handleOutput(cmd.Output())
+	return output
+}
+
 // Files returns all of the files checked in.
 func (git *GitBin) Files(ctx context.Context) []string {
+	return git.listFiles(ctx, &git.files, "ls-files")
+}
+
+// StagedFiles returns all of the files staged for commit that have been added, copied, modified, or renamed,
+// compared to HEAD, or to the base set by SetBase.
+//
+// Like Files, the filenames are relative to the current working directory,
+// and only files at or below the current working directory are returned.
+func (git *GitBin) StagedFiles(ctx context.Context) []string {
+	args := []string{"diff", "--cached", "--name-only", "--diff-filter=ACMR", "--relative"}
+	if git.base != "" {
+		args = append(args, git.base)
+	}
+
+	return git.listFiles(ctx, &git.staged, args...)
+}
+
+// DeletedFiles returns all of the files staged for commit that have been deleted,
+// compared to HEAD, or to the base set by SetBase.
+//
+// Like StagedFiles, the filenames are relative to the current working directory,
+// and only files at or below the current working directory are returned.
+func (git *GitBin) DeletedFiles(ctx context.Context) []string {
+	args := []string{"diff", "--cached", "--name-only", "--diff-filter=D", "--relative"}
+	if git.base != "" {
+		args = append(args, git.base)
+	}
+
+	return git.listFiles(ctx, &git.deleted, args...)
+}
+
+// SetBase sets the commit that StagedFiles and DeletedFiles are compared to, instead of HEAD.
+// As each commit pushed is checked from an index of its own, it also forgets the Files listed from the index before.
+func (git *GitBin) SetBase(rev string) {
+	git.mu.Lock()
+	defer git.mu.Unlock()
+
+	git.base = rev
+	git.files = nil
+	git.staged = nil
+	git.deleted = nil
+}
+
+func (git *GitBin) listFiles(ctx context.Context, cache *map[string][]string, args ...string) []string {
 	git.mu.Lock()
 	defer git.mu.Unlock()
 
@@ -62,14 +331,17 @@ func (git *GitBin) Files(ctx context.Context) []string {
 		panic(fmt.Errorf("could not get current working directory: %w", err))
 	}
 
-	files, ok := git.files[dir]
+	files, ok := (*cache)[dir]
 	if !ok {
-		if git.files == nil {
-			git.files = make(map[string][]string)
+		if *cache == nil {
+			*cache = make(map[string][]string)
+		}
+
+		if output := git.MustOutput(ctx, args...); output != "" {
+			files = strings.Split(output, "\n")
 		}
 
-		files = strings.Split(git.MustOutput(ctx, "ls-files"), "\n")
-		git.files[dir] = files
+		(*cache)[dir] = files
 	}
 
 	return files
@@ -2,61 +2,19 @@ package main
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"go/build"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 )
 
-// GolintBin defines a structured interface to a `golint` binary.
-type GolintBin struct {
-	command
-}
-
-var golintCmd = GolintBin{
-	command: command{
-		Bin: "golint",
-	},
-}
-
-var godocRegexp = regexp.MustCompile(" or be unexported$")
-
-// Lint calls `golint` on the given pkg, and returns true if there are any non-empty lines printed.
-//
-// The first line printed is preceeded by an Error message of the package name.
-// Lines from `golint` will have `trimPrefix` removed from the start of each line.
-func (g *GolintBin) Lint(ctx context.Context, pkg, trimPrefix string, ignoreGodoc bool) bool {
-	var issues int
-
-	output, _ := g.CombinedOutput(ctx, pkg)
-	for _, line := range strings.Split(output, "\n") {
-		if line == "" {
-			continue
-		}
-
-		if ignoreGodoc && godocRegexp.MatchString(line) {
-			continue
-		}
-
-		if issues == 0 {
-			switch pkg {
-			case "":
-				Error("golint", "<root package>")
-			default:
-				Error("golint", pkg)
-			}
-		}
-
-		Warning("golint", strings.TrimPrefix(line, trimPrefix))
-		issues++
-	}
-
-	return issues > 0
-}
-
 // GofmtBin provides a structured interface to a `gofmt` binary.
 type GofmtBin struct {
 	command
@@ -74,6 +32,12 @@ var goimportsCmd = GofmtBin{
 	},
 }
 
+// goimportsPkg is the package that `goimports` is installed from.
+const goimportsPkg = "golang.org/x/tools/cmd/goimports"
+
+// goimportsVersion is the version of `goimports` installed by default, when no version is pinned.
+const goimportsVersion = "v0.36.0"
+
 // List returns all filenames from the given `filenames`,
 // where that file is in need of formatting.
 func (g *GofmtBin) List(ctx context.Context, filenames []string) []string {
@@ -93,6 +57,17 @@ func (g *GofmtBin) List(ctx context.Context, filenames []string) []string {
 	return issues
 }
 
+// Diff returns a unified diff of the formatting changes needed for the given file.
+func (g *GofmtBin) Diff(ctx context.Context, filename string) string {
+	output, _ := g.CombinedOutput(ctx, "-d", filename)
+	return output
+}
+
+// Write rewrites all of the given `filenames` in place with their formatting fixed.
+func (g *GofmtBin) Write(ctx context.Context, filenames []string) (string, bool) {
+	return g.CombinedOutput(ctx, append([]string{"-w"}, filenames...)...)
+}
+
 // GoBin provides a structured interface to a `go` binary.
 type GoBin struct {
 	command
@@ -102,10 +77,23 @@ type GoBin struct {
 
 var goCmd = GoBin{
 	command: command{
-		Bin: env("GO", "go"),
+		Bin:     env("GO", "go"),
+		environ: withoutGitRepoEnv,
 	},
 }
 
+// gitRepoEnv are the variables that point git at a repo, which git sets for hooks, and goprecommit for its snapshots.
+// They are removed from the environment of go, so that they do not leak into tests that run git in repos of their own.
+var gitRepoEnv = []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE"}
+
+// withoutGitRepoEnv returns the environment without any of the gitRepoEnv variables.
+func withoutGitRepoEnv(env []string) []string {
+	return slices.DeleteFunc(env, func(v string) bool {
+		key, _, _ := strings.Cut(v, "=")
+		return slices.Contains(gitRepoEnv, key)
+	})
+}
+
 // Version returns the parsed SemVer returned by the binary.
 func (g *GoBin) Version(ctx context.Context) *SemVer {
 	return g.ver.Get(func() *SemVer {
@@ -131,21 +119,63 @@ func (g *GoBin) Version(ctx context.Context) *SemVer {
 	})
 }
 
-func ensureGopathBinInPath() {
-	gopathBin := filepath.Join(os.Getenv("GOPATH"), "bin")
+// gopathBin returns the directory that `go install` installs binaries into.
+func gopathBin() string {
+	if gobin := os.Getenv("GOBIN"); gobin != "" {
+		return gobin
+	}
 
-	paths := strings.Split(os.Getenv("PATH"), string(os.PathListSeparator))
-	for _, path := range paths {
-		if path == gopathBin {
-			return
+	gopath := filepath.SplitList(build.Default.GOPATH)
+	if len(gopath) == 0 {
+		return filepath.Join(os.Getenv("GOPATH"), "bin")
+	}
+
+	return filepath.Join(gopath[0], "bin")
+}
+
+// inPath returns true if the given directory is in the PATH.
+func inPath(dir string) bool {
+	for _, path := range filepath.SplitList(os.Getenv("PATH")) {
+		if path == dir {
+			return true
 		}
 	}
 
+	return false
+}
+
+func ensureGopathBinInPath() {
+	gopathBin := gopathBin()
+
+	if inPath(gopathBin) {
+		return
+	}
+
+	paths := filepath.SplitList(os.Getenv("PATH"))
 	newPath := strings.Join(append(paths, gopathBin), string(os.PathListSeparator))
 	Warning("putting $GOPATH/bin into PATH", newPath)
 	os.Setenv("PATH", newPath)
 }
 
+// BinaryVersion returns the version of the module that the given go binary was built from.
+// It returns false if the version cannot be found, such as when the binary was not built by go.
+func (g *GoBin) BinaryVersion(ctx context.Context, path string) (string, bool) {
+	output, ok := g.CombinedOutput(ctx, "version", "-m", path)
+	if !ok {
+		return "", false
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+
+		if len(fields) >= 3 && fields[0] == "mod" {
+			return fields[2], true
+		}
+	}
+
+	return "", false
+}
+
 // Install verifies a given `bin` executable is installed,
 // if it is not, then it will install that binary from `pkg` at `latest`.
 //
@@ -156,7 +186,7 @@ func (g *GoBin) Install(ctx context.Context, bin, pkg string) {
 	}
 
 	ver := g.Version(ctx)
-	if ver.Major == 1 && ver.Minor < 16 {
+	if ver.olderThan(minGoVersion) {
 		output, ok := g.CombinedOutput(ctx, "get", "-u", pkg)
 		if !ok {
 			Error(output)
@@ -178,6 +208,32 @@ func (g *GoBin) Install(ctx context.Context, bin, pkg string) {
 	}
 }
 
+// InstallPinned verifies the executable at `path` in the tools cache is installed,
+// if it is not, then it will install that binary from `pkg` at `version` into the directory of `path`,
+// so that once installed, it is available offline.
+//
+// If anything fails to execute, it will print an Error, and exit.
+func (g *GoBin) InstallPinned(ctx context.Context, pkg, version, path string) {
+	if _, err := findBin(path); err == nil {
+		return
+	}
+
+	Verbose("installing", pkg+"@"+version)
+
+	cmd := g.Command(ctx, "install", pkg+"@"+version)
+	cmd.Env = append(cmd.Environ(), "GOBIN="+filepath.Dir(path))
+
+	if output, ok := g.handleOutput(cmd.CombinedOutput()); !ok {
+		Error(output)
+		Exit(1)
+	}
+
+	if _, err := findBin(path); err != nil {
+		Error("after installing binary", err)
+		Exit(1)
+	}
+}
+
 // ModTidy will run `go mod tidy` (or equivalent) in the current working directory.
 func (g *GoBin) ModTidy(ctx context.Context) bool {
 	var howtoTidy string
@@ -211,6 +267,138 @@ func (g *GoBin) ModTidy(ctx context.Context) bool {
 	return ok
 }
 
+// ModTidyDiff returns the changes that `go mod tidy` would make to go.mod and go.sum in the current working directory,
+// as a unified diff, without changing either of them.
+// If `go mod tidy` fails, it returns its output and false.
+//
+// Before go1.23, which added `go mod tidy -diff`, it runs `go mod tidy`,
+// and then restores go.mod and go.sum after comparing them to before.
+func (g *GoBin) ModTidyDiff(ctx context.Context) (string, bool) {
+	ver := g.Version(ctx)
+
+	switch {
+	case ver.Major == 1 && ver.Minor < 11:
+		return "", true
+
+	case ver.Major == 1 && ver.Minor < 23:
+		return g.modTidyCompare(ctx)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := g.Command(ctx, "mod", "tidy", "-diff")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// `go mod tidy -diff` exits with a failure when there are differences,
+	// so it has only truly failed if it did not print a diff.
+	if err := cmd.Run(); err != nil && stdout.Len() == 0 {
+		output, _ := g.handleOutput(stderr.Bytes(), err)
+		return strings.TrimPrefix(output, "go: "), false
+	}
+
+	return stdout.String(), true
+}
+
+func (g *GoBin) modTidyCompare(ctx context.Context) (string, bool) {
+	tmpdir, err := os.MkdirTemp("", "goprecommit-tidy-")
+	if err != nil {
+		Error("tidy", err)
+		Exit(1)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	files := []string{"go.mod", "go.sum"}
+
+	before := make(map[string][]byte)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		before[file] = data
+	}
+
+	defer func() {
+		for _, file := range files {
+			data, ok := before[file]
+			if !ok {
+				os.Remove(file)
+				continue
+			}
+
+			if err := os.WriteFile(file, data, 0644); err != nil {
+				Error("tidy", "restore:", err)
+			}
+		}
+	}()
+
+	if !g.ModTidy(ctx) {
+		return "go mod tidy failed", false
+	}
+
+	var diff strings.Builder
+
+	for _, file := range files {
+		after, _ := os.ReadFile(file)
+
+		if bytes.Equal(before[file], after) {
+			continue
+		}
+
+		for dir, data := range map[string][]byte{"current": before[file], "tidy": after} {
+			name := filepath.Join(tmpdir, dir, file)
+
+			if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+				Error("tidy", err)
+				Exit(1)
+			}
+
+			if err := os.WriteFile(name, data, 0644); err != nil {
+				Error("tidy", err)
+				Exit(1)
+			}
+		}
+
+		diff.WriteString(gitCmd.DiffFiles(ctx, tmpdir, filepath.Join("current", file), filepath.Join("tidy", file)))
+		diff.WriteString("\n")
+	}
+
+	return diff.String(), true
+}
+
+// splitDiff splits a unified diff of multiple files into the diff of each file, keyed by the base name of the file.
+func splitDiff(diff string) map[string]string {
+	files := make(map[string]string)
+
+	var name string
+	var cur strings.Builder
+
+	flush := func() {
+		if name != "" {
+			files[name] = cur.String()
+		}
+
+		cur.Reset()
+	}
+
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if strings.HasPrefix(line, "diff ") {
+			flush()
+
+			fields := strings.Fields(line)
+			name = filepath.Base(fields[len(fields)-1])
+		}
+
+		cur.WriteString(line)
+	}
+
+	flush()
+
+	return files
+}
+
 type goList struct {
 	format string
 }
@@ -256,17 +444,140 @@ func (g *GoBin) List(ctx context.Context, packages any, opts ...GoListOption) []
 	return strings.Split(g.MustOutput(ctx, args.build(pkgs)...), "\n")
 }
 
+// GoPackage is a package as described by `go list -json`, see `go help list`.
+//
+// Only the fields used by goprecommit are decoded.
+type GoPackage struct {
+	Dir        string
+	ImportPath string
+	DepOnly    bool
+
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+
+	EmbedFiles      []string
+	TestEmbedFiles  []string
+	XTestEmbedFiles []string
+}
+
+// ListDeps returns the given packages, and all of their dependencies, as described by `go list -deps -json`.
+// Packages with errors are still returned.
+func (g *GoBin) ListDeps(ctx context.Context, packages ...string) ([]*GoPackage, error) {
+	cmd := g.Command(ctx, append([]string{"list", "-e", "-deps", "-json"}, packages...)...)
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []*GoPackage
+
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for dec.More() {
+		pkg := new(GoPackage)
+		if err := dec.Decode(pkg); err != nil {
+			return nil, err
+		}
+
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+}
+
+type goVet struct {
+	analyzers map[string]bool
+	vettool   string
+}
+
+func (o *goVet) build(pkgs []string) (ret []string) {
+	ret = append(ret, "vet")
+
+	var names []string
+	for name := range o.analyzers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ret = append(ret, fmt.Sprintf("-%s=%t", name, o.analyzers[name]))
+	}
+
+	if o.vettool != "" {
+		ret = append(ret, "-vettool="+o.vettool)
+	}
+
+	return append(ret, pkgs...)
+}
+
+// GoVetOption applies an optional feature onto a GoCmd.Vet command.
+type GoVetOption func(o *goVet)
+
+// WithAnalyzers turns each of the named analyzers on or off during the GoBin.Vet run.
+// As with `go vet`, if any analyzers are turned on, then only those are run.
+func WithAnalyzers(analyzers map[string]bool) GoVetOption {
+	return func(o *goVet) {
+		o.analyzers = analyzers
+	}
+}
+
+// WithVettool will use the given analysis tool instead of the default during the GoBin.Vet run.
+func WithVettool(path string) GoVetOption {
+	return func(o *goVet) {
+		o.vettool = path
+	}
+}
+
+// Vet runs `go vet` on the specified packages, and returns all of the non-empty lines printed,
+// except for the headers naming each package.
+func (g *GoBin) Vet(ctx context.Context, pkgs []string, opts ...GoVetOption) []string {
+	var args goVet
+	for _, opt := range opts {
+		opt(&args)
+	}
+
+	var issues []string
+
+	output, _ := g.CombinedOutput(ctx, args.build(pkgs)...)
+	for _, line := range strings.Split(output, "\n") {
+		if line == "" || strings.HasPrefix(line, "# ") {
+			continue
+		}
+
+		issues = append(issues, line)
+	}
+
+	return issues
+}
+
 type goTest struct {
 	count *int
+	race  bool
+	short bool
+	cover bool
 }
 
 func (o *goTest) build(pkgs []string) (ret []string) {
-	ret = append(ret, "test")
+	ret = append(ret, "test", "-json")
 
 	if o.count != nil {
 		ret = append(ret, fmt.Sprintf("-count=%d", *o.count))
 	}
 
+	if o.race {
+		ret = append(ret, "-race")
+	}
+
+	if o.short {
+		ret = append(ret, "-short")
+	}
+
+	if o.cover {
+		ret = append(ret, "-cover")
+	}
+
 	return append(ret, pkgs...)
 }
 
@@ -288,15 +599,61 @@ func WithCache(flag bool) GoTestOption {
 	}
 }
 
-// Test runs `go test` on the specified packages.
-func (g *GoBin) Test(ctx context.Context, pkgs []string, opts ...GoTestOption) <-chan string {
-	ch := make(chan string)
+// WithRace will enable/disable the race detector during the GoBin.Test run.
+func WithRace(flag bool) GoTestOption {
+	return func(o *goTest) {
+		o.race = flag
+	}
+}
+
+// WithShort will enable/disable `-short` during the GoBin.Test run.
+func WithShort(flag bool) GoTestOption {
+	return func(o *goTest) {
+		o.short = flag
+	}
+}
+
+// WithCover will enable/disable coverage analysis during the GoBin.Test run.
+func WithCover(flag bool) GoTestOption {
+	return func(o *goTest) {
+		o.cover = flag
+	}
+}
+
+// TestEvent is a single event from `go test -json`, see `go doc test2json`.
+//
+// Lines of output that are not JSON, such as those from older versions of Go that print build errors as text,
+// are given as an "output" event with no Package.
+// If `go test` itself fails, this is given as a "fail" event with no Package.
+type TestEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64 // seconds
+	Output  string
+
+	// ImportPath is set instead of Package for "build-output" and "build-fail" events.
+	ImportPath  string
+	FailedBuild string
+}
+
+// Test runs `go test -json` on the specified packages.
+func (g *GoBin) Test(ctx context.Context, pkgs []string, opts ...GoTestOption) <-chan TestEvent {
+	ch := make(chan TestEvent)
 
 	var args goTest
 	for _, opt := range opts {
 		opt(&args)
 	}
 
+	fail := func(err error) {
+		ch <- TestEvent{
+			Action: "fail",
+			Output: err.Error(),
+		}
+	}
+
 	go func() {
 		defer close(ch)
 
@@ -304,28 +661,40 @@ func (g *GoBin) Test(ctx context.Context, pkgs []string, opts ...GoTestOption) <
 
 		output, err := cmd.StdoutPipe()
 		if err != nil {
-			ch <- err.Error()
+			fail(err)
 			return
 		}
 
 		cmd.Stderr = cmd.Stdout
 
 		if err := cmd.Start(); err != nil {
-			ch <- err.Error()
+			fail(err)
 			return
 		}
 
 		s := bufio.NewScanner(output)
+		s.Buffer(nil, 16*1024*1024)
+
 		for s.Scan() {
-			ch <- s.Text()
+			line := s.Bytes()
+
+			var ev TestEvent
+			if !bytes.HasPrefix(line, []byte("{")) || json.Unmarshal(line, &ev) != nil {
+				ev = TestEvent{
+					Action: "output",
+					Output: string(line) + "\n",
+				}
+			}
+
+			ch <- ev
 		}
 
 		if err := s.Err(); err != nil {
-			ch <- err.Error()
+			fail(err)
 		}
 
 		if err := cmd.Wait(); err != nil {
-			ch <- err.Error()
+			fail(err)
 		}
 	}()
 
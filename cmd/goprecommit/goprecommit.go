@@ -5,7 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
+	"slices"
 	"strings"
 
 	flag "github.com/puellanivis/breton/lib/gnuflag"
@@ -20,22 +20,51 @@ var (
 
 // Flags are the flags available in this command.
 var Flags = struct {
-	Cache bool `desc:"use cached test results"`
-	Lint  bool `desc:"use golint"`
-	Color bool `desc:"use color"`
+	Cache    bool `desc:"use cached test results"`
+	Race     bool `desc:"run the go tests with the race detector"`
+	Lint     bool `desc:"use the lint check"`
+	Vet      bool `desc:"use go vet"`
+	Color    bool `desc:"use color"`
+	All      bool `desc:"check all files checked in, not only files staged for commit"`
+	AllTests bool `desc:"run the tests of all packages, not only those affected by the files staged for commit"`
+
+	Snapshot bool `desc:"check a snapshot of the index, rather than the working tree"`
+	Fix      bool `desc:"fix gofmt, goimports, and go mod tidy issues in the working tree, and re-stage the fixed files"`
+
+	Diff      bool `desc:"show the diff of gofmt and goimports issues"`
+	DiffLines int  `desc:"maximum number of lines to show of each diff, unless --verbose"`
+
+	Format   string `desc:"output format: text, json, or sarif"`
+	SarifOut string `desc:"also write a SARIF report to this file"`
+	JunitOut string `desc:"also write a JUnit XML report of the go tests to this file"`
 
 	NoGodoc bool `desc:"don't show godoc issues"`
+
+	Hooks string `desc:"comma-separated list of hooks to install or uninstall"`
+
+	CheckProfile string `desc:"profile of checks to run, as configured in profiles, by default the profile of the hook being run"`
 }{
 	Cache: true,
 	Lint:  true,
+	Vet:   true,
 	Color: true,
+
+	Snapshot: true,
+
+	DiffLines: 20,
+
+	Format: FormatText,
+
+	Hooks: "pre-commit",
 }
 
 func init() {
 	flag.Struct("", &Flags)
 	flag.BoolFunc("nocache", "do not use cached test results", func() { Flags.Cache = false })
-	flag.BoolFunc("nolint", "do not use golint", func() { Flags.Lint = false })
+	flag.BoolFunc("nolint", "do not use the lint check", func() { Flags.Lint = false })
+	flag.BoolFunc("novet", "do not use go vet", func() { Flags.Vet = false })
 	flag.BoolFunc("nocolor", "do not use color", func() { Flags.Color = false })
+	flag.BoolFunc("nosnapshot", "check the working tree, rather than a snapshot of the index", func() { Flags.Snapshot = false })
 }
 
 const pathSep = string(os.PathSeparator)
@@ -47,9 +76,28 @@ func Exit(code int) {
 
 var goModules bool
 
-var generatedCodeMarker = regexp.MustCompile(`^// Code generated by .* DO NOT EDIT\.$`)
+// checkedFiles returns the files that should be checked:
+// by default, the files staged for commit, but with `--all`, every file checked in.
+func checkedFiles(ctx context.Context) []string {
+	if Flags.All {
+		return gitCmd.Files(ctx)
+	}
 
-func precommitCheckModule(ctx context.Context, goMod string) bool {
+	return gitCmd.StagedFiles(ctx)
+}
+
+// deletedFiles returns the files staged for deletion, which cannot be checked themselves,
+// but whose packages, and the packages that import them, must still be checked.
+// With `--all`, every package is checked anyway, so there are none.
+func deletedFiles(ctx context.Context) []string {
+	if Flags.All {
+		return nil
+	}
+
+	return gitCmd.DeletedFiles(ctx)
+}
+
+func precommitCheckModule(ctx context.Context, cfg *Config, workTree, goMod string) bool {
 	Verbose("using go.mod", goMod)
 
 	saveDir, err := os.Getwd()
@@ -75,6 +123,14 @@ func precommitCheckModule(ctx context.Context, goMod string) bool {
 		return false
 	}
 
+	if dir != "." {
+		cfg, err = cfg.LoadConfig(".")
+		if err != nil {
+			Error(goMod, "config:", err)
+			return false
+		}
+	}
+
 	modPath := strings.TrimPrefix(pwd, filepath.Join(os.Getenv("GOPATH"), "src")+pathSep)
 	Verbose("found MOD_PATH", modPath)
 
@@ -84,8 +140,6 @@ func precommitCheckModule(ctx context.Context, goMod string) bool {
 		goModules = false
 	}
 
-	testpkgPrefix := "." + pathSep
-
 	var modBase string
 	if goModules && testCanRead("go.mod") {
 		modBase = getModuleName("go.mod")
@@ -95,26 +149,35 @@ func precommitCheckModule(ctx context.Context, goMod string) bool {
 	Verbose("listing go files…")
 
 	var goFiles []string
-	for _, file := range gitCmd.Files(ctx) {
+	goDirs := make(map[string]bool)
+	for _, file := range checkedFiles(ctx) {
 		if !strings.HasSuffix(file, ".go") {
 			continue
 		}
 
-		if strings.HasPrefix(file, "vendor"+pathSep) {
+		if cfg.IsVendored(file) {
 			continue
 		}
 
+		goDirs[filepath.Dir(file)] = true
+
 		if testEmpty(file) {
 			continue
 		}
 
-		if testFileContains(file, generatedCodeMarker) {
+		if cfg.IsGenerated(file) {
 			continue
 		}
 
 		goFiles = append(goFiles, file)
 	}
 
+	for _, file := range deletedFiles(ctx) {
+		if strings.HasSuffix(file, ".go") && !cfg.IsVendored(file) {
+			goDirs[filepath.Dir(file)] = true
+		}
+	}
+
 	Verbose("found files", len(goFiles))
 
 	Verbose("looking for subrepos…")
@@ -135,27 +198,51 @@ func precommitCheckModule(ctx context.Context, goMod string) bool {
 			subrepos[dirname] = true
 		}
 
-		if strings.HasPrefix(de.Name(), ".") || de.Name() == "vendor" {
+		if strings.HasPrefix(de.Name(), ".") || cfg.IsVendored(de.Name()) {
 			panic(WalkPrune)
 		}
 	})
 
-	var issues int
+	target := &Target{
+		Config:    cfg,
+		Dir:       pwd,
+		Prefix:    dir,
+		WorkTree:  filepath.Join(workTree, dir),
+		Module:    goMod,
+		GoModules: goModules,
+		ModBase:   modBase,
+	}
+
+	issues := runCheckers(ctx, ScopeModule, target)
+	if target.broken && issues > 0 {
+		// the packages cannot be listed, so nothing else can be checked.
+		return false
+	}
 
-	if goModules {
-		Verbose("go mod tidy…")
+	testAll := Flags.All || Flags.AllTests || cfg.Profile().AllTests
 
-		if !goCmd.ModTidy(ctx) {
-			return false
+	var affected map[string]bool
+	if !testAll {
+		Verbose("finding affected packages…")
+
+		var files []string
+		for _, list := range [][]string{checkedFiles(ctx), deletedFiles(ctx)} {
+			for _, file := range list {
+				if !cfg.IsVendored(file) {
+					files = append(files, file)
+				}
+			}
 		}
+
+		affected = affectedPackages(ctx, files)
+		testAll = affected == nil
 	}
 
 	Verbose("listing packages…")
 
-	var gopkgs []string
-	var testPkgs []string
+	var gopkgs, testpkgs []string
 	for _, pkg := range goCmd.List(ctx, "./...") {
-		if strings.Contains(pkg, "/vendor/") {
+		if cfg.IsVendored(pkg) {
 			// older versions of Go could return vendored packages.
 			continue
 		}
@@ -181,179 +268,107 @@ func precommitCheckModule(ctx context.Context, goMod string) bool {
 			continue
 		}
 
+		if testAll || affected[pkg] {
+			testpkgs = append(testpkgs, pkg)
+		}
+
+		if !Flags.All && !goDirs[pkg] {
+			Verbose("package has no staged go files", pkg)
+			continue
+		}
+
 		gopkgs = append(gopkgs, pkg)
-		testPkgs = append(testPkgs, testpkgPrefix+pkg)
 	}
 
 	Verbose("found gopkgs", gopkgs)
+	Verbose("found packages to test", testpkgs)
 
-	if len(goFiles) > 0 {
-		Verbose("gofmt on files…")
+	target.Files = goFiles
+	issues += runCheckers(ctx, ScopeFile, target)
 
-		gofmt := gofmtCmd.List(ctx, goFiles)
+	target.Files = nil
+	target.Packages = gopkgs
+	target.TestPackages = testpkgs
+	issues += runCheckers(ctx, ScopePackage, target)
 
-		inGofmt := make(map[string]bool)
-		for _, file := range gofmt {
-			Error("gofmt", file)
-			inGofmt[file] = true
-			issues++
-		}
+	return issues == 0
+}
 
-		goimports := goimportsCmd.List(ctx, goFiles)
-		for _, file := range goimports {
-			if inGofmt[file] {
-				continue
-			}
+// stagedModules returns only those of `goMods` that contain at least one of the `staged` files.
+// Each staged file belongs only to the go.mod nearest to it.
+func stagedModules(goMods, staged []string) []string {
+	touched := make(map[string]bool)
 
-			Error("goimports", file)
-			issues++
-		}
-	}
+	for _, file := range staged {
+		var nearest string
+		var found bool
 
-	if Flags.Lint && len(gopkgs) > 0 {
-		Verbose("golint on packages…")
+		for _, goMod := range goMods {
+			dir := filepath.Dir(goMod)
 
-		for _, pkg := range gopkgs {
-			if pkg != modBase {
-				pkg = strings.TrimPrefix(pkg, modBase)
-			}
-			if pkg == "/" {
-				pkg = "."
+			if dir != "." && !strings.HasPrefix(file, dir+pathSep) {
+				continue
 			}
-			pkg = strings.TrimPrefix(pkg, pathSep)
 
-			if golintCmd.Lint(ctx, pkg, pwd+pathSep, Flags.NoGodoc) {
-				issues++
+			if !found || len(dir) > len(nearest) {
+				nearest, found = dir, true
 			}
 		}
-	}
-
-	if len(testPkgs) > 0 {
-		Verbose("go test on packages…")
-
-		if modBase != "" {
-			// Exchange modBase for modPath, if it is set.
-			modPath = modBase
-		}
-
-		for line := range goCmd.Test(ctx, testPkgs, WithCache(Flags.Cache)) {
-			switch {
-			case strings.HasPrefix(line, "go: "):
-				// go messages should be shadowed.
-				Hide("go test", line)
-
-			case strings.HasPrefix(line, "ok") && strings.Contains(line, "(cached)"):
-				if strings.Contains(line, "(cached)") {
-					// Cached test results should be low-lighted
-					Info("go test", line)
-				} else {
-					OK("go test", line)
-				}
-
-			case strings.HasPrefix(line, "PASS"):
-				OK("go test", line)
-
-			case line == "FAIL":
-				// Ignore lines that just say "FAIL".
-
-			case strings.HasPrefix(line, "FAIL"), strings.HasPrefix(line, "--- FAIL"):
-				// Failures should be highlighted as errors.
-				Error("go test", line)
-				issues++
-
-			case strings.HasPrefix(line, "panic:"):
-				if strings.Contains(line, "[recovered]") {
-					// recovered panics should be highlighted as warnings.
-					Warning("go test", line)
-				} else {
-					// Unrecovered panics should be highlighted as errors.
-					Error("go test", line)
-				}
-				issues++
-
-			case strings.Contains(line, "cannot find package"):
-				// Not being able to find a package should be highlighted as an error.
-				Error("go test", line)
-				issues++
-
-			case strings.HasPrefix(line, "?") && strings.Contains(line, "[no test files]"):
-				fields := strings.Fields(line)
-				pkg := fields[1]
-
-				// If pkg has a leading underscore, then replace "_${PWD}/" with "./".
-				if try := strings.TrimPrefix(pkg, "_"+pwd); try != pkg {
-					pkg = filepath.Join(".", try)
-				}
-
-				for _, pkgname := range goCmd.List(ctx, pkg, WithFormat("{{.Name}}")) {
-					switch pkgname {
-					case "main":
-						// If a main package does not have tests, then it should be shadowed.
-						Hide("go test", line)
-					default:
-						// Non-main packages with no test files should be lightly highlighted.
-						Notice("go test", line)
-					}
-				}
 
-			default:
-				// Lines that we cannot recognize as anything else should be highlighted as warnings.
-				Warning("go test", strings.ReplaceAll(line, pwd, "."))
-				issues++
-			}
+		if found {
+			touched[nearest] = true
 		}
 	}
 
-	return issues == 0
-}
+	var mods []string
+	for _, goMod := range goMods {
+		if !touched[filepath.Dir(goMod)] {
+			Verbose("no staged files in module", goMod)
+			continue
+		}
 
-func endsWithEOL(filename string) bool {
-	f, err := os.Open(filename)
-	if err != nil {
-		Error("check eol", err)
-		return false
+		mods = append(mods, goMod)
 	}
-	defer f.Close()
 
-	fi, err := f.Stat()
-	if err != nil {
-		Error("check eol", err)
-		return false
-	}
-	if fi.Size() == 0 {
-		// a completely empty file is zero lines of text, and thus valid.
-		return true
-	}
+	return mods
+}
 
-	if _, err := f.Seek(-1, os.SEEK_END); err != nil {
-		Error("check eol", err)
-		return false
+// writeResults writes the results in the output format, and any reports to be written to files.
+//
+// If it fails, it will print an Error, and exit.
+func writeResults() {
+	if err := results.Write(os.Stdout, Flags.Format); err != nil {
+		Error("writing results", err)
+		Exit(1)
 	}
 
-	var buf [1]byte
-
-	if _, err := f.Read(buf[:]); err != nil {
-		Error("check eol", err)
-		return false
+	if Flags.SarifOut != "" {
+		if err := results.WriteFile(Flags.SarifOut, FormatSARIF); err != nil {
+			Error("writing sarif report", err)
+			Exit(1)
+		}
 	}
 
-	return buf[0] == '\n'
+	if junitReport != nil {
+		if err := junitReport.WriteFile(Flags.JunitOut); err != nil {
+			Error("writing junit report", err)
+			Exit(1)
+		}
+	}
 }
 
-func main() {
-	log.SetPrefix("goprecommit: ")
-	log.SetFlags(0)
-
-	ctx, finish := process.Init("goprecommit", Version, Buildstamp)
-	defer finish()
-
-	if !gitCmd.InRepo(ctx) {
-		Verbose("not in git repo")
-		return
+// checkRepo runs every enabled check on the repo with the top-level directory `root`,
+// which is a snapshot, unless it is the working tree itself,
+// and `workTree` is the current working directory in the working tree.
+// The checks of the branch are of the given branch, which is being committed or pushed to.
+// It returns false if any issue should block the commit.
+func checkRepo(ctx context.Context, root, workTree, branch string) bool {
+	cfg, err := DefaultConfig().LoadConfig(root)
+	if err != nil {
+		Error("config", err)
+		return false
 	}
 
-	Verbose("in git repo")
-
 	version := goCmd.Version(ctx)
 	Verbose("found go version", version)
 
@@ -366,9 +381,6 @@ func main() {
 
 	ensureGopathBinInPath()
 
-	goCmd.Install(ctx, "golint", "golang.org/x/lint/golint")
-	goCmd.Install(ctx, "goimports", "golang.org/x/tools/cmd/goimports")
-
 	files := gitCmd.Files(ctx)
 
 	var goMods []string
@@ -379,7 +391,7 @@ func main() {
 		default:
 		}
 
-		if strings.HasPrefix(file, "vendor/") || strings.Contains(file, "/vendor/") {
+		if cfg.IsVendored(file) {
 			continue
 		}
 
@@ -395,40 +407,142 @@ func main() {
 		goMods = append(goMods, filepath.Join(".", "go.mod"))
 	}
 
+	checked := checkedFiles(ctx)
+
+	if !Flags.All {
+		deleted := deletedFiles(ctx)
+
+		Verbose("found staged files", len(checked))
+		Verbose("found deleted files", len(deleted))
+
+		goMods = stagedModules(goMods, append(slices.Clip(checked), deleted...))
+	}
+
 	var blockCommit bool
 	for _, goMod := range goMods {
-		if !precommitCheckModule(ctx, goMod) {
+		ok := precommitCheckModule(ctx, cfg, workTree, goMod)
+		if !ok {
 			blockCommit = true
 		}
+
+		results.AddModule(goMod, ok)
 	}
 
-	branch := gitCmd.Branch(ctx)
-	switch branch {
-	case gitCmd.HeadBranch(ctx), "production", "staging":
-		Error("branch name", "do not commit to ", branch)
+	target := &Target{
+		Config:   cfg,
+		Dir:      root,
+		WorkTree: workTree,
+		Files:    checked,
+		Branch:   branch,
+	}
+
+	if issues := runCheckers(ctx, ScopeRepo, target); issues > 0 {
 		blockCommit = true
 	}
 
-	for _, file := range files {
-		select {
-		case <-ctx.Done():
-			Exit(1)
-		default:
+	return !blockCommit
+}
+
+func main() {
+	log.SetPrefix("goprecommit: ")
+	log.SetFlags(0)
+
+	ctx, finish := process.Init("goprecommit", Version, Buildstamp)
+	defer finish()
+
+	switch Flags.Format {
+	case FormatText:
+	case FormatJSON, FormatSARIF:
+		Flags.Color = false
+	default:
+		Error("unknown output format", Flags.Format)
+		Exit(2)
+	}
+
+	if Flags.SarifOut != "" {
+		// The working directory may change to a snapshot, so resolve the filename now.
+		Flags.SarifOut = mustAbs(Flags.SarifOut)
+	}
+
+	if Flags.JunitOut != "" {
+		Flags.JunitOut = mustAbs(Flags.JunitOut)
+		junitReport = new(JUnitReport)
+	}
+
+	switch cmd := flag.Arg(0); cmd {
+	case "":
+
+	case "install", "uninstall":
+		installCommand(ctx, cmd, flag.Args()[1:])
+
+	case "doctor":
+		doctorCommand(ctx, flag.Args()[1:])
+
+	case "hook":
+		name := flag.Arg(1)
+
+		mode := hookModes[name]
+		if mode == nil {
+			Error("hook", "unknown hook: ", name)
+			Exit(2)
 		}
 
-		if strings.HasSuffix(file, ".jar") {
-			continue
+		if Flags.CheckProfile == "" {
+			Flags.CheckProfile = mode.Profile
 		}
 
-		if strings.Contains(file, ".") {
-			if !endsWithEOL(file) {
-				Error("file doesn't end with EOL", file)
-				blockCommit = true
-			}
+		if mode.Run != nil {
+			mode.Run(ctx, flag.Args()[2:])
+			Exit(0)
+		}
+
+	default:
+		// hooks that do not run the checks can also be run directly, such as `goprecommit commit-msg <file>`.
+		mode := hookModes[cmd]
+		if mode == nil || mode.Run == nil {
+			Error("unknown command", cmd)
+			Exit(2)
+		}
+
+		if Flags.CheckProfile == "" {
+			Flags.CheckProfile = mode.Profile
+		}
+
+		mode.Run(ctx, flag.Args()[1:])
+		Exit(0)
+	}
+
+	if !gitCmd.InRepo(ctx) {
+		Verbose("not in git repo")
+		return
+	}
+
+	Verbose("in git repo")
+
+	root := gitCmd.TopLevel(ctx)
+
+	workTree, err := os.Getwd()
+	if err != nil {
+		Error("getwd", err)
+		Exit(1)
+	}
+
+	if Flags.Snapshot {
+		dir, ok := snapshotIndex(ctx)
+		if !ok {
+			Exit(1)
 		}
+
+		root = dir
 	}
 
-	if blockCommit {
+	branch, _ := gitCmd.Branch(ctx)
+
+	ok := checkRepo(ctx, root, workTree, branch)
+
+	writeResults()
+
+	if !ok {
 		Exit(1)
 	}
 }
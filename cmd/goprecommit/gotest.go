@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// testChecker runs `go test -json` on all of the packages,
+// and reports any build failures, test failures, panics, or unrecognized output.
+type testChecker struct{}
+
+func (testChecker) Name() string { return "test" }
+func (testChecker) Scope() Scope { return ScopePackage }
+
+func (testChecker) DescribeRule(rule string) string {
+	switch rule {
+	case "fail":
+		return "go tests must pass"
+	case "panic":
+		return "go tests must not panic"
+	case "build":
+		return "go tests must build"
+	}
+
+	return "go tests must not produce unrecognized output"
+}
+
+var coverageRegexp = regexp.MustCompile(`coverage: [^\n]*`)
+
+// testRun keeps track of the state of a `go test -json` run.
+type testRun struct {
+	t *Target
+
+	output map[string]*strings.Builder
+	failed map[string]bool
+
+	findings []Finding
+}
+
+func testKey(pkg, test string) string {
+	return pkg + "\x00" + test
+}
+
+func (r *testRun) outputLines(pkg, test string) []string {
+	out := r.output[testKey(pkg, test)]
+	if out == nil {
+		return nil
+	}
+
+	return strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+}
+
+// packageDir returns the directory of the given package import path, relative to the target.
+func (r *testRun) packageDir(pkg string) string {
+	if r.t.ModBase != "" {
+		if rel, ok := strings.CutPrefix(pkg, r.t.ModBase); ok {
+			return filepath.Join(".", strings.TrimPrefix(rel, "/"))
+		}
+	}
+
+	// If pkg has a leading underscore, then replace "_${PWD}/" with "./".
+	if rel, ok := strings.CutPrefix(pkg, "_"+r.t.Dir); ok {
+		return filepath.Join(".", strings.TrimPrefix(rel, pathSep))
+	}
+
+	return pkg
+}
+
+// text handles a line of output that is not attributed to any package or test.
+func (r *testRun) text(line string) {
+	switch {
+	case line == "":
+
+	case strings.HasPrefix(line, "go: "), strings.HasPrefix(line, "# "):
+		// go messages, and build headers should be shadowed.
+		Hide("go test", line)
+
+	default:
+		// Build errors from older versions of Go are printed as text, other text is unrecognized.
+		f := parsePosition("output", strings.ReplaceAll(line, r.t.Dir+pathSep, ""))
+		if f.File != "" {
+			f.Rule = "build"
+		}
+
+		r.findings = append(r.findings, f)
+	}
+}
+
+// testFailed reports the failure of a single test.
+func (r *testRun) testFailed(ev TestEvent) {
+	for failed := range r.failed {
+		if strings.HasPrefix(failed, testKey(ev.Package, ev.Test+"/")) {
+			// A subtest has already been reported as failing, so do not report the parent test as well.
+			r.failed[testKey(ev.Package, ev.Test)] = true
+			return
+		}
+	}
+
+	r.failed[testKey(ev.Package, ev.Test)] = true
+	r.failed[testKey(ev.Package, "")] = true
+
+	f := Finding{
+		Rule:    "fail",
+		Message: fmt.Sprintf("%s failed (%.2fs)", ev.Test, ev.Elapsed),
+	}
+
+	for _, line := range r.outputLines(ev.Package, ev.Test) {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "panic:") {
+			f.Rule = "panic"
+		}
+
+		if f.File == "" {
+			if pos := parsePosition("", trimmed); pos.File != "" {
+				f.File = filepath.Join(r.packageDir(ev.Package), pos.File)
+				f.Line = pos.Line
+				f.Column = pos.Column
+				f.Message = ev.Test + ": " + pos.Message
+
+				continue
+			}
+		}
+
+		if Flags.Format == FormatText {
+			Warning("go test", line)
+		}
+	}
+
+	r.findings = append(r.findings, f)
+}
+
+// packageFailed reports the failure of a package, unless one of its tests has already been reported.
+func (r *testRun) packageFailed(ev TestEvent) {
+	if r.failed[testKey(ev.Package, "")] {
+		return
+	}
+
+	rule := "fail"
+	if ev.FailedBuild != "" || strings.Contains(r.output[testKey(ev.Package, "")].String(), "[build failed]") {
+		for _, f := range r.findings {
+			if f.Rule == "build" {
+				// The build errors have already been reported.
+				return
+			}
+		}
+
+		rule = "build"
+	}
+
+	var msgs []string
+	for _, line := range r.outputLines(ev.Package, "") {
+		if strings.HasPrefix(line, "FAIL") {
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimSpace(line), "panic:") {
+			rule = "panic"
+		}
+
+		msgs = append(msgs, line)
+	}
+
+	msg := ev.Package + " failed"
+	if len(msgs) > 0 {
+		msg += ": " + strings.Join(msgs, "; ")
+	}
+
+	r.findings = append(r.findings, Finding{
+		Rule:    rule,
+		File:    r.packageDir(ev.Package),
+		Message: msg,
+	})
+}
+
+func (testChecker) Run(ctx context.Context, t *Target) []Finding {
+	testpkgPrefix := "." + pathSep
+
+	if len(t.TestPackages) == 0 {
+		return nil
+	}
+
+	var testPkgs []string
+	for _, pkg := range t.TestPackages {
+		testPkgs = append(testPkgs, testpkgPrefix+pkg)
+	}
+
+	r := &testRun{
+		t:      t,
+		output: make(map[string]*strings.Builder),
+		failed: make(map[string]bool),
+	}
+
+	profile := t.Config.Profile()
+
+	opts := []GoTestOption{
+		WithCache(Flags.Cache),
+		WithRace(Flags.Race || profile.Race),
+		WithShort(profile.Short),
+		WithCover(profile.Cover),
+	}
+
+	for ev := range goCmd.Test(ctx, testPkgs, opts...) {
+		if junitReport != nil {
+			junitReport.Event(ev)
+		}
+
+		switch ev.Action {
+		case "output":
+			if ev.Package == "" {
+				r.text(strings.TrimRight(ev.Output, "\n"))
+				break
+			}
+
+			key := testKey(ev.Package, ev.Test)
+
+			out := r.output[key]
+			if out == nil {
+				out = new(strings.Builder)
+				r.output[key] = out
+			}
+
+			out.WriteString(ev.Output)
+
+		case "build-output":
+			r.text(strings.TrimRight(ev.Output, "\n"))
+
+		case "pass":
+			if ev.Test != "" {
+				Verbose("go test", fmt.Sprintf("--- PASS: %s (%.2fs)", ev.Test, ev.Elapsed))
+				break
+			}
+
+			if strings.Contains(r.output[testKey(ev.Package, "")].String(), "(cached)") {
+				// Cached test results should be low-lighted
+				Info("go test", "ok  \t"+ev.Package+"\t(cached)")
+			} else {
+				line := fmt.Sprintf("ok  \t%s\t%.3fs", ev.Package, ev.Elapsed)
+				if cover := coverageRegexp.FindString(r.output[testKey(ev.Package, "")].String()); cover != "" {
+					line += "\t" + cover
+				}
+
+				OK("go test", line)
+			}
+
+		case "skip":
+			if ev.Test != "" {
+				Verbose("go test", fmt.Sprintf("--- SKIP: %s (%.2fs)", ev.Test, ev.Elapsed))
+				break
+			}
+
+			line := "?   \t" + ev.Package + "\t[no test files]"
+
+			for _, pkgname := range goCmd.List(ctx, ev.Package, WithFormat("{{.Name}}")) {
+				switch pkgname {
+				case "main":
+					// If a main package does not have tests, then it should be shadowed.
+					Hide("go test", line)
+				default:
+					// Non-main packages with no test files should be lightly highlighted.
+					Notice("go test", line)
+				}
+			}
+
+		case "fail":
+			switch {
+			case ev.Package == "":
+				// `go test` itself failed, which only needs reporting if nothing else has been.
+				if len(r.findings) == 0 {
+					r.findings = append(r.findings, Finding{
+						Rule:    "fail",
+						Message: "go test failed: " + ev.Output,
+					})
+				}
+
+			case ev.Test != "":
+				r.testFailed(ev)
+
+			default:
+				r.packageFailed(ev)
+			}
+		}
+	}
+
+	return r.findings
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	flag "github.com/puellanivis/breton/lib/gnuflag"
+)
+
+// HookMode describes how goprecommit runs as a particular git hook.
+type HookMode struct {
+	// Stdin is true if git gives the hook input on stdin,
+	// which then also needs to be given to any previous hook that is chained to.
+	Stdin bool
+
+	// Profile is the profile of checks that the hook runs, unless `--check-profile` is given.
+	Profile string
+
+	// Run runs goprecommit as the hook, with the arguments given to the hook by git.
+	// If Run is nil, then the checks are run, as if goprecommit was run with no command.
+	Run func(ctx context.Context, args []string)
+}
+
+// hookModes are all of the git hooks that goprecommit can be installed as.
+var hookModes = map[string]*HookMode{
+	"pre-commit": {
+		Profile: "fast",
+	},
+	"prepare-commit-msg": {
+		Run: prepareCommitMsgHook,
+	},
+	"commit-msg": {
+		Run: commitMsgHook,
+	},
+	"pre-push": {
+		Stdin:   true,
+		Profile: "full",
+		Run:     prePushHook,
+	},
+}
+
+// hookMarker identifies a hook installed by goprecommit.
+const hookMarker = "# installed by goprecommit"
+
+// origSuffix is appended to the name of a hook that was already installed, when goprecommit is installed in its place.
+const origSuffix = ".goprecommit-orig"
+
+var hookTemplate = template.Must(template.New("hook").Parse(`#!/bin/sh
+` + hookMarker + `, do not edit.
+# Any previous {{.Name}} hook was moved to {{.Name}}` + origSuffix + `, and is run first.
+{{if .Stdin}}
+input="$(cat)"
+{{end}}
+orig="$(dirname "$0")/{{.Name}}` + origSuffix + `"
+if [ -x "$orig" ]; then
+	{{if .Stdin}}printf '%s\n' "$input" | {{end}}"$orig" "$@" || exit $?
+fi
+
+{{if .Stdin}}printf '%s\n' "$input" | {{end}}exec {{.Bin}} --short hook {{.Name}} "$@"
+`))
+
+// hookNames returns the names of the hooks given by `--hooks`, after checking that each is a known HookMode.
+func hookNames() ([]string, error) {
+	var names []string
+
+	for _, name := range strings.Split(Flags.Hooks, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if hookModes[name] == nil {
+			var known []string
+			for name := range hookModes {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+
+			return nil, fmt.Errorf("unknown hook %q, must be one of: %s", name, strings.Join(known, ", "))
+		}
+
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		return nil, errors.New("no hooks given")
+	}
+
+	return names, nil
+}
+
+// isGoprecommitHook returns true if the given file is a hook installed by goprecommit.
+func isGoprecommitHook(filename string) bool {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Contains(data, []byte(hookMarker))
+}
+
+// installHook installs goprecommit as the named hook in the given hooks directory.
+// Any existing hook is moved aside, so that it is chained to, and can be restored by uninstallHook.
+func installHook(dir, name, bin string) error {
+	filename := filepath.Join(dir, name)
+	orig := filename + origSuffix
+
+	switch _, err := os.Lstat(filename); {
+	case errors.Is(err, os.ErrNotExist):
+
+	case err != nil:
+		return err
+
+	case isGoprecommitHook(filename):
+		Verbose(name, "updating installed hook")
+
+	default:
+		if _, err := os.Lstat(orig); err == nil {
+			return fmt.Errorf("%s already exists, refusing to replace it", orig)
+		}
+
+		if err := os.Rename(filename, orig); err != nil {
+			return err
+		}
+
+		Info(name, "moved existing hook to ", orig)
+	}
+
+	var buf bytes.Buffer
+	if err := hookTemplate.Execute(&buf, map[string]any{
+		"Name":  name,
+		"Stdin": hookModes[name].Stdin,
+		"Bin":   shellQuote(bin),
+	}); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, buf.Bytes(), 0755)
+}
+
+// uninstallHook removes goprecommit as the named hook in the given hooks directory,
+// and restores any hook that it replaced.
+func uninstallHook(dir, name string) error {
+	filename := filepath.Join(dir, name)
+	orig := filename + origSuffix
+
+	if _, err := os.Lstat(filename); err == nil {
+		if !isGoprecommitHook(filename) {
+			return fmt.Errorf("%s was not installed by goprecommit, refusing to remove it", filename)
+		}
+
+		if err := os.Remove(filename); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Lstat(orig); err != nil {
+		return nil
+	}
+
+	if err := os.Rename(orig, filename); err != nil {
+		return err
+	}
+
+	Info(name, "restored previous hook")
+
+	return nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// installCommand runs the `install` and `uninstall` commands, and then exits.
+func installCommand(ctx context.Context, cmd string, args []string) {
+	if err := flag.CommandLine.Parse(args); err != nil {
+		Error(cmd, err)
+		Exit(2)
+	}
+
+	if flag.NArg() > 0 {
+		Error(cmd, "unexpected arguments: ", strings.Join(flag.Args(), " "))
+		Exit(2)
+	}
+
+	names, err := hookNames()
+	if err != nil {
+		Error(cmd, err)
+		Exit(2)
+	}
+
+	if !gitCmd.InRepo(ctx) {
+		Error("not in git repo")
+		Exit(1)
+	}
+
+	dir := gitCmd.HooksDir(ctx)
+
+	bin, err := os.Executable()
+	if err != nil {
+		Error(cmd, err)
+		Exit(1)
+	}
+
+	var failed bool
+
+	for _, name := range names {
+		var err error
+
+		switch cmd {
+		case "install":
+			err = installHook(dir, name, bin)
+		case "uninstall":
+			err = uninstallHook(dir, name)
+		}
+
+		if err != nil {
+			Error(name, err)
+			failed = true
+			continue
+		}
+
+		OK(name, cmd+"ed in ", dir)
+	}
+
+	if failed {
+		Exit(1)
+	}
+
+	Exit(0)
+}
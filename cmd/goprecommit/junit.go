@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name          `xml:"testsuites"`
+	Suites  []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+
+	output strings.Builder
+}
+
+type junitTestCase struct {
+	Classname string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+
+	output strings.Builder
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Output  string `xml:",chardata"`
+}
+
+// JUnitReport collects the events of `go test -json` runs, so that they can be written out as JUnit XML.
+type JUnitReport struct {
+	mu sync.Mutex
+
+	suites []*junitTestSuite
+	byName map[string]*junitTestSuite
+}
+
+var junitReport *JUnitReport
+
+func junitSeconds(elapsed float64) string {
+	return strconv.FormatFloat(elapsed, 'f', 3, 64)
+}
+
+func (r *JUnitReport) suite(pkg string) *junitTestSuite {
+	if suite := r.byName[pkg]; suite != nil {
+		return suite
+	}
+
+	if r.byName == nil {
+		r.byName = make(map[string]*junitTestSuite)
+	}
+
+	suite := &junitTestSuite{
+		Name: pkg,
+		Time: junitSeconds(0),
+	}
+
+	r.suites = append(r.suites, suite)
+	r.byName[pkg] = suite
+
+	return suite
+}
+
+func (s *junitTestSuite) testcase(name string) *junitTestCase {
+	for _, tc := range s.Cases {
+		if tc.Name == name {
+			return tc
+		}
+	}
+
+	tc := &junitTestCase{
+		Classname: s.Name,
+		Name:      name,
+		Time:      junitSeconds(0),
+	}
+
+	s.Cases = append(s.Cases, tc)
+	s.Tests++
+
+	return tc
+}
+
+// Event records a single event from `go test -json`.
+func (r *JUnitReport) Event(ev TestEvent) {
+	if ev.Package == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	suite := r.suite(ev.Package)
+
+	if ev.Test == "" {
+		switch ev.Action {
+		case "output":
+			suite.output.WriteString(ev.Output)
+
+		case "pass", "skip":
+			suite.Time = junitSeconds(ev.Elapsed)
+
+		case "fail":
+			suite.Time = junitSeconds(ev.Elapsed)
+
+			if suite.Failures == 0 {
+				// The package failed without any tests failing, e.g. it failed to build.
+				tc := suite.testcase("(package)")
+				tc.Failure = &junitMessage{
+					Message: "failed",
+					Output:  suite.output.String(),
+				}
+				suite.Failures++
+			}
+		}
+
+		return
+	}
+
+	tc := suite.testcase(ev.Test)
+
+	switch ev.Action {
+	case "output":
+		tc.output.WriteString(ev.Output)
+
+	case "pass":
+		tc.Time = junitSeconds(ev.Elapsed)
+		tc.SystemOut = tc.output.String()
+
+	case "skip":
+		tc.Time = junitSeconds(ev.Elapsed)
+		tc.Skipped = &junitMessage{
+			Message: "skipped",
+			Output:  tc.output.String(),
+		}
+		suite.Skipped++
+
+	case "fail":
+		tc.Time = junitSeconds(ev.Elapsed)
+		tc.Failure = &junitMessage{
+			Message: "failed",
+			Output:  tc.output.String(),
+		}
+		suite.Failures++
+	}
+}
+
+// Write writes the collected test results to w as JUnit XML.
+func (r *JUnitReport) Write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+
+	if err := enc.Encode(junitTestSuites{Suites: r.suites}); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// WriteFile writes the collected test results to the given file as JUnit XML.
+func (r *JUnitReport) WriteFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
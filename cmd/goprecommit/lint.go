@@ -0,0 +1,470 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+// docAnalyzer reports exported identifiers that are missing a doc comment,
+// or whose doc comment does not begin with their name.
+// As with golint, methods that implement common interfaces, such as String, need not be documented.
+var docAnalyzer = &analysis.Analyzer{
+	Name: "doccomment",
+	Doc:  "exported identifiers must have doc comments beginning with their name",
+	Run:  runDoc,
+}
+
+// styleAnalyzers are the style rules of the lint check, in the spirit of golint and revive.
+var styleAnalyzers = []*analysis.Analyzer{
+	{
+		Name: "receivernames",
+		Doc:  "receiver names must be short, consistent, and not generic names such as this or self",
+		Run:  runReceiverNames,
+	},
+	{
+		Name: "errorstrings",
+		Doc:  "error strings must not be capitalized or end with punctuation or a newline",
+		Run:  runErrorStrings,
+	},
+	{
+		Name: "errornames",
+		Doc:  "error variables must be named errFoo or ErrFoo",
+		Run:  runErrorNames,
+	},
+	{
+		Name: "incdec",
+		Doc:  "x += 1 and x -= 1 must be written as x++ and x--",
+		Run:  runIncDec,
+	},
+	{
+		Name: "indenterrorflow",
+		Doc:  "an if block that ends with a return must not be followed by an else block",
+		Run:  runIndentErrorFlow,
+	},
+}
+
+// lintFiles returns the files of the pass that should be linted, skipping generated files.
+func lintFiles(pass *analysis.Pass) []*ast.File {
+	var files []*ast.File
+
+	for _, file := range pass.Files {
+		if ast.IsGenerated(file) {
+			continue
+		}
+
+		files = append(files, file)
+	}
+
+	return files
+}
+
+func isTestFile(pass *analysis.Pass, file *ast.File) bool {
+	return strings.HasSuffix(pass.Fset.File(file.Pos()).Name(), "_test.go")
+}
+
+// receiverType returns the name of the type of the given receiver, without any pointer or type parameters.
+func receiverType(recv *ast.FieldList) string {
+	if recv == nil || len(recv.List) == 0 {
+		return ""
+	}
+
+	expr := recv.List[0].Type
+	for {
+		switch t := expr.(type) {
+		case *ast.StarExpr:
+			expr = t.X
+		case *ast.ParenExpr:
+			expr = t.X
+		case *ast.IndexExpr:
+			expr = t.X
+		case *ast.IndexListExpr:
+			expr = t.X
+		case *ast.Ident:
+			return t.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// commonMethods are the names of methods that implement well-known interfaces,
+// which need not be documented, as their meaning is already known.
+var commonMethods = map[string]bool{
+	"Error":     true,
+	"Read":      true,
+	"ServeHTTP": true,
+	"String":    true,
+	"Write":     true,
+	"Unwrap":    true,
+}
+
+// docHasName returns true if the doc comment begins with the given name, or is a deprecation notice.
+// As with golint, the name of a type may also be preceded by an article.
+func docHasName(doc *ast.CommentGroup, name string, articles bool) bool {
+	text := strings.TrimSpace(doc.Text())
+
+	if strings.HasPrefix(text, "Deprecated:") || beginsWithName(text, name) {
+		return true
+	}
+
+	if !articles {
+		return false
+	}
+
+	for _, article := range []string{"A ", "An ", "The "} {
+		if rest, ok := strings.CutPrefix(text, article); ok && beginsWithName(rest, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// beginsWithName returns true if the text begins with the given name, as a whole word.
+func beginsWithName(text, name string) bool {
+	rest, ok := strings.CutPrefix(text, name)
+	if !ok {
+		return false
+	}
+
+	r, _ := utf8.DecodeRuneInString(rest)
+	return rest == "" || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+// checkDoc reports if the doc comment of the exported identifier is missing, or does not begin with its name.
+// The identifier is described by its kind, and its qualified name, such as the type of a method.
+func checkDoc(pass *analysis.Pass, pos token.Pos, doc *ast.CommentGroup, kind, qualified, name string) {
+	if doc == nil {
+		pass.Reportf(pos, "exported %s %s should have comment or be unexported", kind, qualified)
+		return
+	}
+
+	if !docHasName(doc, name, kind == "type") {
+		pass.Reportf(doc.Pos(), "comment on exported %s %s should be of the form \"%s ...\"", kind, qualified, name)
+	}
+}
+
+func runDoc(pass *analysis.Pass) (any, error) {
+	files := lintFiles(pass)
+
+	var nonTest []*ast.File
+	for _, file := range files {
+		if !isTestFile(pass, file) {
+			nonTest = append(nonTest, file)
+		}
+	}
+
+	for _, file := range nonTest {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if !decl.Name.IsExported() {
+					continue
+				}
+
+				if decl.Recv == nil {
+					checkDoc(pass, decl.Pos(), decl.Doc, "function", decl.Name.Name, decl.Name.Name)
+					continue
+				}
+
+				recv := receiverType(decl.Recv)
+				if !ast.IsExported(recv) || commonMethods[decl.Name.Name] {
+					continue
+				}
+
+				checkDoc(pass, decl.Pos(), decl.Doc, "method", recv+"."+decl.Name.Name, decl.Name.Name)
+
+			case *ast.GenDecl:
+				docGenDecl(pass, decl)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func docGenDecl(pass *analysis.Pass, decl *ast.GenDecl) {
+	grouped := decl.Lparen.IsValid()
+
+	for _, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			if !spec.Name.IsExported() {
+				continue
+			}
+
+			doc := spec.Doc
+			if doc == nil && !grouped {
+				doc = decl.Doc
+			}
+
+			checkDoc(pass, spec.Pos(), doc, "type", spec.Name.Name, spec.Name.Name)
+
+		case *ast.ValueSpec:
+			kind := "var"
+			if decl.Tok == token.CONST {
+				kind = "const"
+			}
+
+			// only the first exported name of a spec needs to be documented.
+			var name *ast.Ident
+			for _, ident := range spec.Names {
+				if ident.IsExported() {
+					name = ident
+					break
+				}
+			}
+
+			if name == nil {
+				continue
+			}
+
+			switch {
+			case spec.Doc != nil:
+				if !grouped && !docHasName(spec.Doc, name.Name, false) {
+					pass.Reportf(spec.Doc.Pos(), "comment on exported %s %s should be of the form \"%s ...\"", kind, name.Name, name.Name)
+				}
+
+			case decl.Doc != nil:
+				if !grouped && !docHasName(decl.Doc, name.Name, false) {
+					pass.Reportf(decl.Doc.Pos(), "comment on exported %s %s should be of the form \"%s ...\"", kind, name.Name, name.Name)
+				}
+
+			case grouped:
+				pass.Reportf(name.Pos(), "exported %s %s should have comment (or a comment on this block) or be unexported", kind, name.Name)
+
+			default:
+				pass.Reportf(name.Pos(), "exported %s %s should have comment or be unexported", kind, name.Name)
+			}
+		}
+	}
+}
+
+func runReceiverNames(pass *analysis.Pass) (any, error) {
+	names := make(map[string]string)
+
+	for _, file := range lintFiles(pass) {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 {
+				continue
+			}
+
+			field := fn.Recv.List[0]
+			if len(field.Names) == 0 {
+				continue
+			}
+
+			recv := field.Names[0]
+			switch recv.Name {
+			case "_":
+				continue
+
+			case "this", "self":
+				pass.Reportf(recv.Pos(), "receiver name should be a reflection of its identity; don't use generic names such as %q", recv.Name)
+				continue
+			}
+
+			typ := receiverType(fn.Recv)
+			if typ == "" {
+				continue
+			}
+
+			prev, ok := names[typ]
+			if !ok {
+				names[typ] = recv.Name
+				continue
+			}
+
+			if prev != recv.Name {
+				pass.Reportf(recv.Pos(), "receiver name %s should be consistent with previous receiver name %s for %s", recv.Name, prev, typ)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// isErrorConstructor returns true if the call is to errors.New or fmt.Errorf.
+func isErrorConstructor(pass *analysis.Pass, call *ast.CallExpr) bool {
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return false
+	}
+
+	switch fn.Pkg().Path() + "." + fn.Name() {
+	case "errors.New", "fmt.Errorf":
+		return true
+	}
+
+	return false
+}
+
+func lintErrorString(s string) bool {
+	if s == "" {
+		return true
+	}
+
+	switch s[len(s)-1] {
+	case '.', ':', '!', '\n':
+		return false
+	}
+
+	first, n := utf8.DecodeRuneInString(s)
+	if !unicode.IsUpper(first) {
+		return true
+	}
+
+	// allow acronyms and initialisms, such as "URL" or "EOF".
+	second, _ := utf8.DecodeRuneInString(s[n:])
+	return unicode.IsUpper(second) || !unicode.IsLetter(second)
+}
+
+func runErrorStrings(pass *analysis.Pass) (any, error) {
+	for _, file := range lintFiles(pass) {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 || !isErrorConstructor(pass, call) {
+				return true
+			}
+
+			tv, ok := pass.TypesInfo.Types[call.Args[0]]
+			if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+				return true
+			}
+
+			if !lintErrorString(constant.StringVal(tv.Value)) {
+				pass.Reportf(call.Args[0].Pos(), "error strings should not be capitalized or end with punctuation or a newline")
+			}
+
+			return true
+		})
+	}
+
+	return nil, nil
+}
+
+func runErrorNames(pass *analysis.Pass) (any, error) {
+	for _, file := range lintFiles(pass) {
+		for _, decl := range file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok || gd.Tok != token.VAR {
+				continue
+			}
+
+			for _, spec := range gd.Specs {
+				vs := spec.(*ast.ValueSpec)
+
+				for i, name := range vs.Names {
+					if i >= len(vs.Values) || name.Name == "_" {
+						continue
+					}
+
+					call, ok := vs.Values[i].(*ast.CallExpr)
+					if !ok || !isErrorConstructor(pass, call) {
+						continue
+					}
+
+					prefix := "err"
+					if name.IsExported() {
+						prefix = "Err"
+					}
+
+					if !strings.HasPrefix(name.Name, prefix) {
+						pass.Reportf(name.Pos(), "error var %s should have name of the form %sFoo", name.Name, prefix)
+					}
+				}
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func runIncDec(pass *analysis.Pass) (any, error) {
+	for _, file := range lintFiles(pass) {
+		ast.Inspect(file, func(n ast.Node) bool {
+			as, ok := n.(*ast.AssignStmt)
+			if !ok || len(as.Lhs) != 1 || len(as.Rhs) != 1 {
+				return true
+			}
+
+			var op string
+			switch as.Tok {
+			case token.ADD_ASSIGN:
+				op = "++"
+			case token.SUB_ASSIGN:
+				op = "--"
+			default:
+				return true
+			}
+
+			tv, ok := pass.TypesInfo.Types[as.Rhs[0]]
+			if !ok || tv.Value == nil || tv.Value.String() != "1" {
+				return true
+			}
+
+			lhs := types.ExprString(as.Lhs[0])
+			pass.Report(analysis.Diagnostic{
+				Pos:     as.Pos(),
+				End:     as.End(),
+				Message: fmt.Sprintf("should replace %s %s 1 with %s%s", lhs, as.Tok, lhs, op),
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message: "replace with " + lhs + op,
+					TextEdits: []analysis.TextEdit{{
+						Pos:     as.Pos(),
+						End:     as.End(),
+						NewText: []byte(lhs + op),
+					}},
+				}},
+			})
+
+			return true
+		})
+	}
+
+	return nil, nil
+}
+
+func runIndentErrorFlow(pass *analysis.Pass) (any, error) {
+	for _, file := range lintFiles(pass) {
+		ast.Inspect(file, func(n ast.Node) bool {
+			ifStmt, ok := n.(*ast.IfStmt)
+			if !ok || ifStmt.Else == nil {
+				return true
+			}
+
+			if _, ok := ifStmt.Else.(*ast.BlockStmt); !ok {
+				// else if chains are fine.
+				return true
+			}
+
+			// variables declared in the if statement would go out of scope if the else block is outdented.
+			if as, ok := ifStmt.Init.(*ast.AssignStmt); ok && as.Tok == token.DEFINE {
+				return true
+			}
+
+			body := ifStmt.Body.List
+			if len(body) == 0 {
+				return true
+			}
+
+			if _, ok := body[len(body)-1].(*ast.ReturnStmt); ok {
+				pass.Reportf(ifStmt.Else.Pos(), "if block ends with a return statement, so drop this else and outdent its block")
+			}
+
+			return true
+		})
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestDocAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), docAnalyzer, "doccomment")
+}
+
+// TestStyleAnalyzers runs each of the styleAnalyzers on the package in testdata named after it.
+func TestStyleAnalyzers(t *testing.T) {
+	for _, a := range styleAnalyzers {
+		t.Run(a.Name, func(t *testing.T) {
+			analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), a, a.Name)
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// golangciLintConfigs are the names of the configuration files of `golangci-lint`.
+var golangciLintConfigs = []string{".golangci.yml", ".golangci.yaml", ".golangci.toml", ".golangci.json"}
+
+// staticcheckConfigs are the names of the configuration files of `staticcheck`.
+var staticcheckConfigs = []string{"staticcheck.conf"}
+
+// findConfig returns the first of the given configuration files that exists in the given directory,
+// or else in the nearest of its parent directories, up to and including the top-level directory top,
+// as the linters themselves look for their configuration in parent directories.
+func findConfig(dir, top string, names []string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		for _, name := range names {
+			file := filepath.Join(dir, name)
+			if testCanRead(file) {
+				return file, true
+			}
+		}
+
+		rel, err := filepath.Rel(top, dir)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return "", false
+		}
+
+		dir = filepath.Dir(dir)
+	}
+}
+
+// GolangciLintBin provides a structured interface to a `golangci-lint` binary.
+type GolangciLintBin struct {
+	command
+}
+
+var golangciLintCmd = GolangciLintBin{
+	command: command{
+		Bin: "golangci-lint",
+	},
+}
+
+// golangciLintVersions are the versions of `golangci-lint` installed by default, by major version,
+// when no version is pinned.
+var golangciLintVersions = map[string]string{
+	"v1": "v1.64.8",
+	"v2": "v2.1.6",
+}
+
+var golangciLintV2Regexp = regexp.MustCompile(`(?m)^\s*"?version"?\s*[:=]\s*["']?2`)
+
+// golangciLintMajor returns the major version of `golangci-lint` that the given configuration file is for.
+func golangciLintMajor(filename string) string {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "v1"
+	}
+
+	if golangciLintV2Regexp.Match(data) {
+		return "v2"
+	}
+
+	return "v1"
+}
+
+// golangciLintPkg returns the package that `golangci-lint` of the given major version is installed from.
+func golangciLintPkg(major string) string {
+	if major == "v1" {
+		return "github.com/golangci/golangci-lint/cmd/golangci-lint"
+	}
+
+	return "github.com/golangci/golangci-lint/" + major + "/cmd/golangci-lint"
+}
+
+// Install makes `golangci-lint` of the given major version available, see installTool.
+func (g *GolangciLintBin) Install(ctx context.Context, cfg *Config, major string) {
+	installTool(ctx, cfg, &g.command, golangciLintPkg(major), golangciLintVersions[major])
+}
+
+// GolangciLintIssue is an issue reported by `golangci-lint` in its JSON output.
+type GolangciLintIssue struct {
+	FromLinter string
+	Text       string
+	Pos        struct {
+		Filename string
+		Line     int
+		Column   int
+	}
+}
+
+// Run runs `golangci-lint` on the given packages, and returns all of the issues found.
+// If it fails, it returns its output and false.
+func (g *GolangciLintBin) Run(ctx context.Context, major string, pkgs []string) ([]GolangciLintIssue, string, bool) {
+	args := []string{"run", "--out-format=json"}
+	if major != "v1" {
+		args = []string{"run", "--output.json.path=stdout", "--show-stats=false"}
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := g.Command(ctx, append(args, pkgs...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// `golangci-lint` exits with a failure when it finds issues,
+	// so it has only truly failed if it did not print a report.
+	err := cmd.Run()
+
+	var report struct {
+		Issues []GolangciLintIssue
+	}
+
+	if jsonErr := json.Unmarshal(stdout.Bytes(), &report); jsonErr != nil {
+		if err == nil {
+			err = jsonErr
+		}
+
+		output, _ := g.handleOutput(stderr.Bytes(), err)
+		if output == "" {
+			output = err.Error()
+		}
+
+		return nil, output, false
+	}
+
+	return report.Issues, "", true
+}
+
+// StaticcheckBin provides a structured interface to a `staticcheck` binary.
+type StaticcheckBin struct {
+	command
+}
+
+var staticcheckCmd = StaticcheckBin{
+	command: command{
+		Bin: "staticcheck",
+	},
+}
+
+// staticcheckVersion is the version of `staticcheck` installed by default, when no version is pinned.
+const staticcheckVersion = "2025.1.1"
+
+// Install makes `staticcheck` available, see installTool.
+func (s *StaticcheckBin) Install(ctx context.Context, cfg *Config) {
+	installTool(ctx, cfg, &s.command, "honnef.co/go/tools/cmd/staticcheck", staticcheckVersion)
+}
+
+// StaticcheckIssue is an issue reported by `staticcheck` in its JSON output.
+type StaticcheckIssue struct {
+	Code     string `json:"code"`
+	Location struct {
+		File   string `json:"file"`
+		Line   int    `json:"line"`
+		Column int    `json:"column"`
+	} `json:"location"`
+	Message string `json:"message"`
+}
+
+// Run runs `staticcheck` on the given packages, and returns all of the issues found.
+// If it fails, it returns its output and false.
+func (s *StaticcheckBin) Run(ctx context.Context, pkgs []string) ([]StaticcheckIssue, string, bool) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := s.Command(ctx, append([]string{"-f", "json"}, pkgs...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// `staticcheck` exits with a failure when it finds issues.
+	err := cmd.Run()
+
+	var issues []StaticcheckIssue
+
+	sc := bufio.NewScanner(&stdout)
+	sc.Buffer(nil, 16*1024*1024)
+
+	for sc.Scan() {
+		var issue StaticcheckIssue
+		if err := json.Unmarshal(sc.Bytes(), &issue); err != nil {
+			return nil, sc.Text(), false
+		}
+
+		issues = append(issues, issue)
+	}
+
+	if err != nil && len(issues) == 0 {
+		output, _ := s.handleOutput(stderr.Bytes(), err)
+		return nil, output, false
+	}
+
+	return issues, "", true
+}
+
+// relativeFile returns the given filename relative to dir, if it is within dir.
+func relativeFile(dir, filename string) string {
+	if !filepath.IsAbs(filename) {
+		return filepath.Clean(filename)
+	}
+
+	rel, err := filepath.Rel(dir, filename)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filename
+	}
+
+	return rel
+}
+
+// relativePackages returns the given packages as relative package paths, as accepted by go tools.
+func relativePackages(pkgs []string) []string {
+	var rel []string
+	for _, pkg := range pkgs {
+		rel = append(rel, "."+pathSep+pkg)
+	}
+
+	return rel
+}
+
+// golangciLintChecker reports the issues found by `golangci-lint`,
+// in modules that have a configuration file for it, in the module or any parent directory up to the top-level.
+type golangciLintChecker struct{}
+
+func (golangciLintChecker) Name() string { return "golangci-lint" }
+func (golangciLintChecker) Scope() Scope { return ScopePackage }
+
+func (golangciLintChecker) DescribeRule(rule string) string {
+	if rule == "failed" {
+		return "golangci-lint must succeed"
+	}
+
+	return "go packages must have no " + rule + " issues from golangci-lint"
+}
+
+func (golangciLintChecker) Run(ctx context.Context, t *Target) []Finding {
+	if len(t.Packages) == 0 {
+		return nil
+	}
+
+	config, ok := findConfig(".", checkedTopLevel(ctx), golangciLintConfigs)
+	if !ok {
+		Verbose("golangci-lint", "no configuration found")
+		return nil
+	}
+
+	major := golangciLintMajor(config)
+	golangciLintCmd.Install(ctx, t.Config, major)
+
+	issues, output, ok := golangciLintCmd.Run(ctx, major, relativePackages(t.Packages))
+	if !ok {
+		return []Finding{{
+			Rule:    "failed",
+			Message: "golangci-lint failed: " + output,
+		}}
+	}
+
+	var findings []Finding
+	for _, issue := range issues {
+		findings = append(findings, Finding{
+			Rule:    issue.FromLinter,
+			File:    relativeFile(t.Dir, issue.Pos.Filename),
+			Line:    issue.Pos.Line,
+			Column:  issue.Pos.Column,
+			Message: issue.Text,
+		})
+	}
+
+	return findings
+}
+
+// staticcheckChecker reports the issues found by `staticcheck`,
+// in modules that have a configuration file for it, in the module or any parent directory up to the top-level.
+type staticcheckChecker struct{}
+
+func (staticcheckChecker) Name() string { return "staticcheck" }
+func (staticcheckChecker) Scope() Scope { return ScopePackage }
+
+func (staticcheckChecker) DescribeRule(rule string) string {
+	if rule == "failed" {
+		return "staticcheck must succeed"
+	}
+
+	return "go packages must have no " + rule + " issues from staticcheck"
+}
+
+func (staticcheckChecker) Run(ctx context.Context, t *Target) []Finding {
+	if len(t.Packages) == 0 {
+		return nil
+	}
+
+	if _, ok := findConfig(".", checkedTopLevel(ctx), staticcheckConfigs); !ok {
+		Verbose("staticcheck", "no configuration found")
+		return nil
+	}
+
+	staticcheckCmd.Install(ctx, t.Config)
+
+	issues, output, ok := staticcheckCmd.Run(ctx, relativePackages(t.Packages))
+	if !ok {
+		return []Finding{{
+			Rule:    "failed",
+			Message: "staticcheck failed: " + output,
+		}}
+	}
+
+	var findings []Finding
+	for _, issue := range issues {
+		findings = append(findings, Finding{
+			Rule:    issue.Code,
+			File:    relativeFile(t.Dir, issue.Location.File),
+			Line:    issue.Location.Line,
+			Column:  issue.Location.Column,
+			Message: issue.Message,
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+)
+
+// RefUpdate is a single ref update that is being pushed, as given to the pre-push hook on stdin.
+type RefUpdate struct {
+	LocalRef, LocalSHA   string
+	RemoteRef, RemoteSHA string
+}
+
+// readRefUpdates reads the ref updates given to the pre-push hook on stdin.
+func readRefUpdates() ([]RefUpdate, error) {
+	var updates []RefUpdate
+
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 4 {
+			continue
+		}
+
+		updates = append(updates, RefUpdate{
+			LocalRef:  fields[0],
+			LocalSHA:  fields[1],
+			RemoteRef: fields[2],
+			RemoteSHA: fields[3],
+		})
+	}
+
+	return updates, sc.Err()
+}
+
+// isZero returns true if the given commit is all zeros, which git gives in place of the commit of a ref that does not exist,
+// such as a new remote branch, or a deleted local one.
+func isZero(sha string) bool {
+	return strings.Trim(sha, "0") == ""
+}
+
+// pushBase returns the commit that the pushed commit should be compared to:
+// the merge base with the commit being replaced on the remote,
+// or for a new remote branch, the merge base with the head branch of the remote.
+// It returns false if there is no such commit, and then every file should be checked.
+func pushBase(ctx context.Context, remote string, u RefUpdate) (string, bool) {
+	if !isZero(u.RemoteSHA) {
+		if base, ok := gitCmd.MergeBase(ctx, u.LocalSHA, u.RemoteSHA); ok {
+			return base, true
+		}
+
+		// such as when the remote has commits that have not been fetched.
+		Verbose(u.RemoteRef, "remote commit not known ", u.RemoteSHA)
+	}
+
+	head, ok := gitCmd.RemoteHead(ctx, remote)
+	if !ok {
+		return "", false
+	}
+
+	return gitCmd.MergeBase(ctx, u.LocalSHA, "refs/remotes/"+remote+"/"+head)
+}
+
+// prePushHook runs goprecommit as the pre-push hook, which runs every enabled check of its profile
+// on the commit of each branch being pushed, for the files changed since the commit on the remote.
+// Pushes to protected branches are refused, as are commits to them.
+func prePushHook(ctx context.Context, args []string) {
+	if len(args) < 1 {
+		Error("pre-push", "expected the name of the remote")
+		Exit(2)
+	}
+
+	remote := args[0]
+
+	updates, err := readRefUpdates()
+	if err != nil {
+		Error("pre-push", err)
+		Exit(1)
+	}
+
+	if !gitCmd.InRepo(ctx) {
+		Error("not in git repo")
+		Exit(1)
+	}
+
+	workTree, err := os.Getwd()
+	if err != nil {
+		Error("getwd", err)
+		Exit(1)
+	}
+
+	// the checks are of the commits pushed, so fixes in the working tree would not be pushed.
+	Flags.Fix = false
+
+	ok := true
+
+	for _, u := range updates {
+		branch, isBranch := strings.CutPrefix(u.RemoteRef, "refs/heads/")
+		if !isBranch {
+			Verbose(u.RemoteRef, "not a branch")
+			continue
+		}
+
+		if isZero(u.LocalSHA) {
+			Verbose(u.RemoteRef, "deleting branch")
+			// the protected branch checks do not need the files, so only those are run.
+			if !checkDeletedBranch(ctx, workTree, branch) {
+				ok = false
+			}
+
+			continue
+		}
+
+		Notice("pre-push", u.LocalRef, " to ", remote, " ", branch)
+
+		base, found := pushBase(ctx, remote, u)
+		gitCmd.SetBase(base)
+
+		if found {
+			Verbose("pre-push", "checking files changed since ", base)
+		} else {
+			Verbose("pre-push", "no commit in common with ", remote, ", checking all files")
+		}
+
+		saveAll := Flags.All
+		Flags.All = Flags.All || !found
+
+		root, snapshotOK := snapshotCommit(ctx, u.LocalSHA)
+		if !snapshotOK {
+			Exit(1)
+		}
+
+		if !checkRepo(ctx, root, workTree, branch) {
+			ok = false
+		}
+
+		Flags.All = saveAll
+
+		if err := os.Chdir(workTree); err != nil {
+			Error("pre-push", "chdir:", err)
+			Exit(1)
+		}
+	}
+
+	writeResults()
+
+	if !ok {
+		Exit(1)
+	}
+}
+
+// checkDeletedBranch runs only the checks of the branch on a branch being deleted from the remote,
+// so that protected branches cannot be deleted.
+func checkDeletedBranch(ctx context.Context, workTree, branch string) bool {
+	cfg, err := DefaultConfig().LoadConfig(gitCmd.TopLevel(ctx))
+	if err != nil {
+		Error("config", err)
+		return false
+	}
+
+	if !cfg.Enabled("branch") {
+		return true
+	}
+
+	t := &Target{
+		Config:   cfg,
+		Dir:      workTree,
+		WorkTree: workTree,
+		Branch:   branch,
+	}
+
+	var findings []Finding
+	for _, f := range (branchChecker{}).Run(ctx, t) {
+		if f.Rule == "name" {
+			// the name of a branch does not matter when it is being deleted.
+			continue
+		}
+
+		f.Check = "branch"
+		f.Severity = cfg.Severity("branch")
+
+		findings = append(findings, f)
+	}
+
+	var blocking int
+	for _, f := range findings {
+		if f.Blocks() {
+			blocking++
+		}
+
+		if Flags.Format == FormatText {
+			Report(f)
+		}
+	}
+
+	results.AddCheck("", "branch", findings, blocking == 0)
+
+	return blocking == 0
+}
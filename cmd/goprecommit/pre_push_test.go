@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// runIn runs the command in the given directory, and returns its combined output, and whether it succeeded.
+func runIn(t *testing.T, dir, name string, args ...string) (string, bool) {
+	t.Helper()
+
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(withoutGitRepoEnv(os.Environ()),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull,
+	)
+
+	output, err := cmd.CombinedOutput()
+	return string(output), err == nil
+}
+
+// mustRunIn runs the command in the given directory, and fails the test if it does not succeed.
+func mustRunIn(t *testing.T, dir, name string, args ...string) {
+	t.Helper()
+
+	if output, ok := runIn(t, dir, name, args...); !ok {
+		t.Fatalf("%s %v: %s", name, args, output)
+	}
+}
+
+// writeFiles writes each of the files, by their name relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, data := range files {
+		filename := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestPrePushRefs pushes a branch that passes together with a branch that fails,
+// and so every ref pushed must be checked against its own commit, not that of the ref before it.
+func TestPrePushRefs(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds goprecommit, and runs git and go")
+	}
+
+	tmp := t.TempDir()
+
+	bin := filepath.Join(tmp, "goprecommit")
+	mustRunIn(t, ".", "go", "build", "-o", bin, ".")
+
+	remote := filepath.Join(tmp, "remote.git")
+	repo := filepath.Join(tmp, "repo")
+
+	mustRunIn(t, tmp, "git", "init", "-q", "--bare", remote)
+	mustRunIn(t, tmp, "git", "init", "-q", "-b", "main", repo)
+
+	writeFiles(t, repo, map[string]string{
+		"go.mod":            "module example.com/push\n\ngo 1.21\n",
+		"push.go":           "package push\n\n// One returns 1.\nfunc One() int { return 1 }\n",
+		"push_test.go":      "package push\n\nimport \"testing\"\n\nfunc TestOne(t *testing.T) {\n\tif One() != 1 {\n\t\tt.Fatal(\"not one\")\n\t}\n}\n",
+		".goprecommit.yaml": "checks:\n  goimports:\n    enabled: false\n",
+	})
+
+	mustRunIn(t, repo, "git", "add", "-A")
+	mustRunIn(t, repo, "git", "commit", "-q", "-m", "feat: one")
+	mustRunIn(t, repo, "git", "remote", "add", "origin", remote)
+	mustRunIn(t, repo, "git", "push", "-q", "origin", "main")
+	mustRunIn(t, repo, "git", "remote", "set-head", "origin", "main")
+	mustRunIn(t, repo, bin, "install", "--hooks=pre-push")
+
+	mustRunIn(t, repo, "git", "switch", "-q", "-c", "ok-branch")
+	writeFiles(t, repo, map[string]string{
+		"two.go": "package push\n\n// Two returns 2.\nfunc Two() int { return 2 }\n",
+	})
+	mustRunIn(t, repo, "git", "add", "-A")
+	mustRunIn(t, repo, "git", "commit", "-q", "-m", "feat: two")
+
+	mustRunIn(t, repo, "git", "switch", "-q", "-c", "bad-branch", "main")
+	writeFiles(t, repo, map[string]string{
+		"sub/go.mod":      "module example.com/push/sub\n\ngo 1.21\n",
+		"sub/doc.go":      "// Package sub fails its tests.\npackage sub\n",
+		"sub/sub_test.go": "package sub\n\nimport \"testing\"\n\nfunc TestFails(t *testing.T) {\n\tt.Fatal(\"fails\")\n}\n",
+	})
+	mustRunIn(t, repo, "git", "add", "-A")
+	mustRunIn(t, repo, "git", "commit", "-q", "-m", "test: fails")
+
+	if output, ok := runIn(t, repo, "git", "push", "origin", "ok-branch", "bad-branch"); ok {
+		t.Fatalf("push of ok-branch and bad-branch succeeded, want refused:\n%s", output)
+	}
+
+	if output, ok := runIn(t, repo, "git", "push", "origin", "bad-branch", "ok-branch"); ok {
+		t.Fatalf("push of bad-branch and ok-branch succeeded, want refused:\n%s", output)
+	}
+
+	if output, ok := runIn(t, repo, "git", "push", "origin", "ok-branch"); !ok {
+		t.Fatalf("push of ok-branch refused:\n%s", output)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Ticket positions that are defined:
+const (
+	TicketPrefix  = "prefix"
+	TicketTrailer = "trailer"
+)
+
+// skippedMsgSources are the sources of a commit message given to the prepare-commit-msg hook,
+// that a ticket is not added to, because the message is of a merge, a squash, or an existing commit.
+var skippedMsgSources = map[string]bool{
+	"merge":  true,
+	"squash": true,
+	"commit": true,
+}
+
+// branchTicket returns the ticket found in the given branch name by the configured pattern.
+// It returns false if there is no pattern, or no ticket is found.
+func branchTicket(cfg *TicketConfig, branch string) (string, bool) {
+	if cfg.Pattern == "" {
+		return "", false
+	}
+
+	re, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return "", false
+	}
+
+	m := re.FindStringSubmatch(branch)
+	switch {
+	case m == nil:
+		return "", false
+	case len(m) > 1:
+		return m[1], m[1] != ""
+	}
+
+	return m[0], true
+}
+
+// prefixTicket returns the header with the ticket added as its prefix.
+// In a Conventional Commits header, the ticket starts the subject, so that the header is still valid:
+// `feat(api): PLAT-1 add x`. Otherwise, the ticket starts the header: `PLAT-1: add x`.
+func prefixTicket(header, ticket string) string {
+	if m := conventionalHeaderRegexp.FindStringSubmatchIndex(header); m != nil {
+		// the start of the subject.
+		i := m[8]
+		return header[:i] + ticket + " " + header[i:]
+	}
+
+	return ticket + ": " + header
+}
+
+// addTicket adds the ticket to the commit message in the given file,
+// unless the message already contains the ticket as a whole word.
+// Comment lines, and anything after the scissors line, are kept as they are.
+func addTicket(cfg *TicketConfig, filename, commentChar, ticket string) (bool, error) {
+	lines, err := readCommitMsg(filename, commentChar)
+	if err != nil {
+		return false, err
+	}
+
+	// a message with PLAT-12 does not contain PLAT-1.
+	re := regexp.MustCompile(`\b` + regexp.QuoteMeta(ticket) + `\b`)
+
+	for _, line := range lines {
+		if re.MatchString(line.text) {
+			return false, nil
+		}
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false, err
+	}
+
+	raw := strings.Split(string(data), "\n")
+
+	insert := func(i int, text ...string) {
+		raw = append(raw[:i], append(text, raw[i:]...)...)
+	}
+
+	switch cfg.Position {
+	case TicketPrefix:
+		if len(lines) == 0 {
+			if raw[0] != "" {
+				insert(0, "")
+			}
+
+			raw[0] = ticket + ": "
+			break
+		}
+
+		i := lines[0].n - 1
+		raw[i] = prefixTicket(raw[i], ticket)
+
+	case TicketTrailer:
+		trailer := cfg.Trailer + ": " + ticket
+
+		if len(lines) == 0 {
+			// leave the first line for the header to be written in the editor.
+			if raw[0] != "" {
+				insert(0, "")
+			}
+
+			insert(1, "", trailer)
+			break
+		}
+
+		i := lines[len(lines)-1].n
+
+		if _, trailers := splitTrailers(lines[1:]); trailers != nil {
+			insert(i, trailer)
+		} else {
+			insert(i, "", trailer)
+		}
+	}
+
+	return true, os.WriteFile(filename, []byte(strings.Join(raw, "\n")), 0644)
+}
+
+// prepareCommitMsgHook runs goprecommit as the prepare-commit-msg hook,
+// which adds the ticket found in the name of the current branch to the commit message in the given file.
+func prepareCommitMsgHook(ctx context.Context, args []string) {
+	if len(args) < 1 || len(args) > 3 {
+		Error("prepare-commit-msg", "expected the file of the commit message, and optionally its source")
+		Exit(2)
+	}
+
+	filename := args[0]
+
+	if len(args) > 1 && skippedMsgSources[args[1]] {
+		Verbose("prepare-commit-msg", "not adding ticket to message from ", args[1])
+		return
+	}
+
+	if !gitCmd.InRepo(ctx) {
+		Error("not in git repo")
+		Exit(1)
+	}
+
+	cfg, err := DefaultConfig().LoadConfig(gitCmd.TopLevel(ctx))
+	if err != nil {
+		Error("config", err)
+		Exit(1)
+	}
+
+	branch, ok := gitCmd.Branch(ctx)
+	if !ok {
+		Verbose("prepare-commit-msg", "HEAD is detached")
+		return
+	}
+
+	ticket, ok := branchTicket(&cfg.Ticket, branch)
+	if !ok {
+		Verbose("prepare-commit-msg", "no ticket found in branch ", branch)
+		return
+	}
+
+	added, err := addTicket(&cfg.Ticket, filename, gitCmd.CommentChar(ctx), ticket)
+	if err != nil {
+		Error("prepare-commit-msg", err)
+		Exit(1)
+	}
+
+	if added {
+		Verbose("prepare-commit-msg", "added ticket ", ticket)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestBranchTicket(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		branch  string
+		want    string
+		wantOK  bool
+	}{
+		{
+			name:   "no pattern",
+			branch: "PLAT-1-add-x",
+		},
+		{
+			name:    "invalid pattern",
+			pattern: `[A-Z+-\d+`,
+			branch:  "PLAT-1-add-x",
+		},
+		{
+			name:    "no ticket",
+			pattern: `[A-Z]+-\d+`,
+			branch:  "add-x",
+		},
+		{
+			name:    "whole match",
+			pattern: `[A-Z]+-\d+`,
+			branch:  "feature/PLAT-1-add-x",
+			want:    "PLAT-1",
+			wantOK:  true,
+		},
+		{
+			name:    "first group",
+			pattern: `^[a-z]+/(\d+)-`,
+			branch:  "feature/1234-add-x",
+			want:    "1234",
+			wantOK:  true,
+		},
+		{
+			name:    "empty group",
+			pattern: `^[a-z]+/(\d*)`,
+			branch:  "feature/add-x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &TicketConfig{
+				Pattern: tt.pattern,
+			}
+
+			got, ok := branchTicket(cfg, tt.branch)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("branchTicket() = %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPrefixTicket(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"feat: add x", "feat: PLAT-1 add x"},
+		{"feat(api): add x", "feat(api): PLAT-1 add x"},
+		{"feat(api)!: add x", "feat(api)!: PLAT-1 add x"},
+		{"add x", "PLAT-1: add x"},
+		{"feat:add x", "PLAT-1: feat:add x"},
+	}
+
+	for _, tt := range tests {
+		if got := prefixTicket(tt.header, "PLAT-1"); got != tt.want {
+			t.Errorf("prefixTicket(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestAddTicket(t *testing.T) {
+	prefix := &TicketConfig{
+		Position: TicketPrefix,
+	}
+
+	trailer := &TicketConfig{
+		Position: TicketTrailer,
+		Trailer:  "Refs",
+	}
+
+	tests := []struct {
+		name      string
+		cfg       *TicketConfig
+		msg       string
+		want      string
+		wantAdded bool
+	}{
+		{
+			name:      "prefix",
+			cfg:       prefix,
+			msg:       "feat: add x\n",
+			want:      "feat: PLAT-1 add x\n",
+			wantAdded: true,
+		},
+		{
+			name:      "prefix of header after comments",
+			cfg:       prefix,
+			msg:       "# comment\n\nadd x\n\nBody text.\n",
+			want:      "# comment\n\nPLAT-1: add x\n\nBody text.\n",
+			wantAdded: true,
+		},
+		{
+			name:      "prefix of empty message",
+			cfg:       prefix,
+			msg:       "\n# comment\n",
+			want:      "PLAT-1: \n# comment\n",
+			wantAdded: true,
+		},
+		{
+			name:      "prefix of empty message from template",
+			cfg:       prefix,
+			msg:       "# comment\n",
+			want:      "PLAT-1: \n# comment\n",
+			wantAdded: true,
+		},
+		{
+			name:      "trailer",
+			cfg:       trailer,
+			msg:       "feat: add x\n",
+			want:      "feat: add x\n\nRefs: PLAT-1\n",
+			wantAdded: true,
+		},
+		{
+			name:      "trailer after body",
+			cfg:       trailer,
+			msg:       "feat: add x\n\nBody text.\n\n# comment\n",
+			want:      "feat: add x\n\nBody text.\n\nRefs: PLAT-1\n\n# comment\n",
+			wantAdded: true,
+		},
+		{
+			name:      "trailer after trailers",
+			cfg:       trailer,
+			msg:       "feat: add x\n\nBody text.\n\nSigned-off-by: A <a@example.com>\n",
+			want:      "feat: add x\n\nBody text.\n\nSigned-off-by: A <a@example.com>\nRefs: PLAT-1\n",
+			wantAdded: true,
+		},
+		{
+			name:      "trailer before scissors",
+			cfg:       trailer,
+			msg:       "feat: add x\n#" + scissors + "\ndiff --git a/x b/x\n",
+			want:      "feat: add x\n\nRefs: PLAT-1\n#" + scissors + "\ndiff --git a/x b/x\n",
+			wantAdded: true,
+		},
+		{
+			name:      "trailer of empty message",
+			cfg:       trailer,
+			msg:       "\n# comment\n",
+			want:      "\n\nRefs: PLAT-1\n# comment\n",
+			wantAdded: true,
+		},
+		{
+			name: "already in header",
+			cfg:  prefix,
+			msg:  "feat: PLAT-1 add x\n",
+			want: "feat: PLAT-1 add x\n",
+		},
+		{
+			name: "already in trailer",
+			cfg:  prefix,
+			msg:  "feat: add x\n\nRefs: PLAT-1\n",
+			want: "feat: add x\n\nRefs: PLAT-1\n",
+		},
+		{
+			name:      "only another ticket with the same start",
+			cfg:       prefix,
+			msg:       "feat: add x\n\nRefs: PLAT-12\n",
+			want:      "feat: PLAT-1 add x\n\nRefs: PLAT-12\n",
+			wantAdded: true,
+		},
+		{
+			name:      "only in comments",
+			cfg:       trailer,
+			msg:       "feat: add x\n# On branch PLAT-1-add-x\n",
+			want:      "feat: add x\n\nRefs: PLAT-1\n# On branch PLAT-1-add-x\n",
+			wantAdded: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeCommitMsg(t, tt.msg)
+
+			added, err := addTicket(tt.cfg, filename, "#", "PLAT-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if added != tt.wantAdded {
+				t.Errorf("addTicket() = %v, want %v", added, tt.wantAdded)
+			}
+
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := string(data); got != tt.want {
+				t.Errorf("addTicket() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Output formats that are defined:
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// ModuleResult is the outcome of checking a single module.
+type ModuleResult struct {
+	Module string `json:"module"`
+	Pass   bool   `json:"pass"`
+}
+
+// CheckResult is the outcome of running a single check on a module, or on the repo.
+type CheckResult struct {
+	Module   string `json:"module,omitempty"`
+	Check    string `json:"check"`
+	Pass     bool   `json:"pass"`
+	Findings int    `json:"findings"`
+}
+
+// Results collects the outcome of every check run, so that it can be written out in a machine-readable format.
+type Results struct {
+	mu sync.Mutex
+
+	Version  string         `json:"version"`
+	Pass     bool           `json:"pass"`
+	Modules  []ModuleResult `json:"modules"`
+	Checks   []CheckResult  `json:"checks"`
+	Findings []Finding      `json:"findings"`
+}
+
+var results Results
+
+// AddModule records the outcome of checking the given module.
+func (r *Results) AddModule(module string, pass bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Modules = append(r.Modules, ModuleResult{
+		Module: module,
+		Pass:   pass,
+	})
+}
+
+// AddCheck records the outcome, and all the findings, of running the named check on the given module.
+func (r *Results) AddCheck(module, check string, findings []Finding, pass bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Checks = append(r.Checks, CheckResult{
+		Module:   module,
+		Check:    check,
+		Pass:     pass,
+		Findings: len(findings),
+	})
+
+	r.Findings = append(r.Findings, findings...)
+}
+
+// pass returns true if every module, and every check passed.
+func (r *Results) pass() bool {
+	for _, m := range r.Modules {
+		if !m.Pass {
+			return false
+		}
+	}
+
+	for _, c := range r.Checks {
+		if !c.Pass {
+			return false
+		}
+	}
+
+	return true
+}
+
+// WriteFile writes the results to the given file in the given format.
+func (r *Results) WriteFile(filename, format string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Write writes the results to w in the given format.
+// The text format has already been printed while checking, so nothing is written for it.
+func (r *Results) Write(w io.Writer, format string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Version = Version
+	r.Pass = r.pass()
+
+	switch format {
+	case FormatJSON:
+		if r.Modules == nil {
+			r.Modules = []ModuleResult{}
+		}
+
+		if r.Checks == nil {
+			r.Checks = []CheckResult{}
+		}
+
+		if r.Findings == nil {
+			r.Findings = []Finding{}
+		}
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+
+		return enc.Encode(r)
+
+	case FormatSARIF:
+		return writeSARIF(w, r.Findings)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// SARIF 2.1.0 constants, see: https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string            `json:"name"`
+	Version        string            `json:"version"`
+	InformationURI string            `json:"informationUri"`
+	Properties     map[string]string `json:"properties,omitempty"`
+	Rules          []sarifRule       `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string             `json:"id"`
+	Name             string             `json:"name"`
+	ShortDescription sarifMessage       `json:"shortDescription"`
+	DefaultConfig    sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+func sarifLevel(sev Severity) string {
+	if sev >= SeverityError {
+		return "error"
+	}
+
+	return "warning"
+}
+
+// writeSARIF writes the given findings to w as a SARIF log.
+func writeSARIF(w io.Writer, findings []Finding) error {
+	driver := sarifDriver{
+		Name:           "goprecommit",
+		Version:        Version,
+		InformationURI: "https://github.com/puellanivis/goprecommit",
+		Properties: map[string]string{
+			"buildstamp": Buildstamp,
+		},
+		Rules: []sarifRule{},
+	}
+
+	ruleIndex := make(map[string]int)
+	results := []sarifResult{}
+
+	for _, f := range findings {
+		id := f.Check
+		if f.Rule != "" {
+			id += "/" + f.Rule
+		}
+
+		idx, ok := ruleIndex[id]
+		if !ok {
+			idx = len(driver.Rules)
+			ruleIndex[id] = idx
+
+			driver.Rules = append(driver.Rules, sarifRule{
+				ID:   id,
+				Name: id,
+				ShortDescription: sarifMessage{
+					Text: describeRule(f.Check, f.Rule),
+				},
+				DefaultConfig: sarifConfiguration{
+					Level: sarifLevel(f.Severity),
+				},
+			})
+		}
+
+		result := sarifResult{
+			RuleID:    id,
+			RuleIndex: idx,
+			Level:     sarifLevel(f.Severity),
+			Message: sarifMessage{
+				Text: f.Message,
+			},
+		}
+
+		if f.File != "" {
+			loc := sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{
+						URI: filepathToURI(f.File),
+					},
+				},
+			}
+
+			if f.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{
+					StartLine:   f.Line,
+					StartColumn: f.Column,
+				}
+			}
+
+			result.Locations = append(result.Locations, loc)
+		}
+
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: driver,
+				},
+				Results: results,
+			},
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+
+	return enc.Encode(log)
+}
@@ -66,3 +66,12 @@ func ParseVersion(input string) (*SemVer, error) {
 func (v *SemVer) String() string {
 	return fmt.Sprintf("v%d.%d.%d%s", v.Major, v.Minor, v.Patch, v.Details)
 }
+
+// olderThan returns true if the version is older than the given version, ignoring the patch version.
+func (v *SemVer) olderThan(o SemVer) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+
+	return v.Minor < o.Minor
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// snapshotIndex exports the contents of the git index into the snapshot directory of the repo,
+// and changes the current working directory into the equivalent directory in that snapshot.
+// It returns the top-level directory of the snapshot.
+//
+// Git commands are pointed at the snapshot as their working tree,
+// so that files listed from git line up with the files in the snapshot.
+//
+// The snapshot directory is kept between runs, and only refreshed,
+// so that the packages in it keep the same paths, and go can cache their builds and test results.
+func snapshotIndex(ctx context.Context) (string, bool) {
+	return snapshot(ctx, "snapshot", "")
+}
+
+// snapshotCommit exports the contents of the given commit into its own snapshot directory, as does snapshotIndex.
+//
+// Git commands are also pointed at an index of its own holding the commit,
+// so that it is the commit which files are listed from, and staged files are compared to.
+func snapshotCommit(ctx context.Context, rev string) (string, bool) {
+	return snapshot(ctx, "commit-snapshot", rev)
+}
+
+// snapshotDir is the top-level directory of the snapshot last taken,
+// and snapshotTop is the top-level directory of the working tree that it is a snapshot of.
+var snapshotDir, snapshotTop string
+
+// workTreePath returns the path in the working tree of the given path,
+// if it is in the snapshot last taken, otherwise it returns the path unchanged.
+func workTreePath(path string) string {
+	if snapshotDir == "" {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	rel, err := filepath.Rel(snapshotDir, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+
+	return filepath.Join(snapshotTop, rel)
+}
+
+// checkedTopLevel returns the top-level directory of the files being checked,
+// which is the snapshot last taken, if any, otherwise the top-level directory of the working tree.
+func checkedTopLevel(ctx context.Context) string {
+	if snapshotDir != "" {
+		return snapshotDir
+	}
+
+	return gitCmd.TopLevel(ctx)
+}
+
+func snapshot(ctx context.Context, name, rev string) (string, bool) {
+	top := gitCmd.TopLevel(ctx)
+	gitDir := gitCmd.GitDir(ctx)
+
+	pwd, err := os.Getwd()
+	if err != nil {
+		Error("snapshot", "getwd:", err)
+		return "", false
+	}
+
+	rel, err := filepath.Rel(top, pwd)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = "."
+	}
+
+	dir := gitCmd.GitPath(ctx, filepath.Join("goprecommit", name))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		Error("snapshot", err)
+		return "", false
+	}
+
+	if rev != "" {
+		gitCmd.SetEnv("GIT_INDEX_FILE=" + dir + ".index")
+
+		Verbose("reading tree of", rev)
+
+		if output, ok := gitCmd.ReadTree(ctx, rev); !ok {
+			Error("git read-tree", output)
+			return "", false
+		}
+	}
+
+	Verbose("exporting index to", dir)
+
+	if output, ok := gitCmd.CheckoutIndex(ctx, dir); !ok {
+		Error("git checkout-index", output)
+		return "", false
+	}
+
+	if !pruneSnapshot(ctx, dir) {
+		return "", false
+	}
+
+	if err := os.Chdir(filepath.Join(dir, rel)); err != nil {
+		Error("snapshot", "chdir:", err)
+		return "", false
+	}
+
+	gitCmd.SetEnv("GIT_DIR="+gitDir, "GIT_WORK_TREE="+dir)
+	snapshotDir, snapshotTop = dir, top
+
+	return dir, true
+}
+
+// pruneSnapshot removes every file from the snapshot directory that is no longer in the index,
+// along with any directories left empty.
+func pruneSnapshot(ctx context.Context, dir string) bool {
+	files, ok := gitCmd.IndexFiles(ctx)
+	if !ok {
+		Error("snapshot", "could not list files in index")
+		return false
+	}
+
+	inIndex := make(map[string]bool)
+	for _, file := range files {
+		inIndex[file] = true
+	}
+
+	var dirs []string
+
+	err := filepath.WalkDir(dir, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if de.IsDir() {
+			if rel != "." {
+				dirs = append(dirs, path)
+			}
+
+			return nil
+		}
+
+		if !inIndex[rel] {
+			Verbose("snapshot", "removing ", rel)
+			return os.Remove(path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		Error("snapshot", "prune:", err)
+		return false
+	}
+
+	// deepest first, so that directories only holding empty directories are removed too.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		// only empty directories can be removed, so failing to remove any other is expected.
+		_ = os.Remove(dirs[i])
+	}
+
+	return true
+}
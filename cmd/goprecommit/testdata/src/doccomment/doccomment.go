@@ -0,0 +1,77 @@
+package doccomment
+
+func Undocumented() {} // want `exported function Undocumented should have comment or be unexported`
+
+// Documented does nothing.
+func Documented() {}
+
+// does nothing. // want `comment on exported function Wrong should be of the form "Wrong ..."`
+func Wrong() {}
+
+// WrongPrefix is not the name of this function. // want `comment on exported function Wrong2 should be of the form "Wrong2 ..."`
+func Wrong2() {}
+
+// A returns an a, and is not preceded by an article.
+func A() string { return "a" }
+
+// The Articled function may not be preceded by an article. // want `comment on exported function Articled should be of the form "Articled ..."`
+func Articled() {}
+
+// Deprecated: use Documented.
+func Old() {}
+
+func unexported() {}
+
+// A Thing may be preceded by an article.
+type Thing struct{}
+
+// Thingy is not the name of this type. // want `comment on exported type Thin should be of the form "Thin ..."`
+type Thin struct{}
+
+// String need not be documented, as it implements a common interface.
+func (Thing) String() string { return "thing" }
+
+func (Thing) Error() string { return "thing" }
+
+func (Thing) Method() {} // want `exported method Thing.Method should have comment or be unexported`
+
+// a method. // want `comment on exported method Thing.Other should be of the form "Other ..."`
+func (*Thing) Other() {}
+
+type hidden struct{}
+
+func (hidden) Method() {}
+
+type (
+	// Inner is documented.
+	Inner int
+
+	Bare int // want `exported type Bare should have comment or be unexported`
+)
+
+var Exported = 1 // want `exported var Exported should have comment or be unexported`
+
+// Answer is the answer.
+const Answer = 42
+
+// wrong. // want `comment on exported var Wrongly should be of the form "Wrongly ..."`
+var Wrongly = 2
+
+var unexportedVar, Second = 1, 2 // want `exported var Second should have comment or be unexported`
+
+const (
+	Grouped = 1 // want `exported const Grouped should have comment \(or a comment on this block\) or be unexported`
+)
+
+// Block documents the block.
+const (
+	InBlock = 1
+)
+
+var (
+	// InGroup is documented.
+	InGroup = 1
+
+	// not the name, but a comment in a group need not begin with it.
+	GroupComment = 2
+)
@@ -0,0 +1,22 @@
+package errornames
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	errGood = errors.New("good")
+	ErrGood = fmt.Errorf("good")
+
+	badErr = errors.New("bad") // want `error var badErr should have name of the form errFoo`
+	BadErr = fmt.Errorf("bad") // want `error var BadErr should have name of the form ErrFoo`
+
+	_      = errors.New("blank")
+	notErr = "not an error"
+)
+
+func local() error {
+	bad := errors.New("local variables are not checked")
+	return bad
+}
@@ -0,0 +1,27 @@
+package errorstrings
+
+import (
+	"errors"
+	"fmt"
+)
+
+const prefix = "Something"
+
+var (
+	_ = errors.New("Something failed")  // want `error strings should not be capitalized or end with punctuation or a newline`
+	_ = errors.New("something failed.") // want `error strings should not be capitalized or end with punctuation or a newline`
+	_ = fmt.Errorf("failed:")           // want `error strings should not be capitalized or end with punctuation or a newline`
+	_ = fmt.Errorf("%d failed\n", 1)    // want `error strings should not be capitalized or end with punctuation or a newline`
+	_ = errors.New(prefix + " failed")  // want `error strings should not be capitalized or end with punctuation or a newline`
+
+	_ = errors.New("something failed")
+	_ = errors.New("URL is not valid")
+	_ = errors.New("I failed")
+	_ = errors.New("")
+	_ = fmt.Errorf("%d things failed", 1)
+	_ = fmt.Sprintf("Not an error.")
+)
+
+func dynamic(msg string) error {
+	return errors.New(msg)
+}
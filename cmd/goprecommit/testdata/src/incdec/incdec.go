@@ -0,0 +1,20 @@
+package incdec
+
+func count(xs []int) int {
+	var n int
+
+	for _, x := range xs {
+		n += 1 // want `should replace n \+= 1 with n\+\+`
+
+		if x < 0 {
+			n -= 1 // want `should replace n -= 1 with n--`
+		}
+
+		xs[0] += 1 // want `should replace xs\[0\] \+= 1 with xs\[0\]\+\+`
+
+		n += 2
+		n += x
+	}
+
+	return n
+}
@@ -0,0 +1,40 @@
+package indenterrorflow
+
+func get() (int, bool) { return 1, true }
+
+func returns(b bool) int {
+	if b {
+		return 1
+	} else { // want `if block ends with a return statement, so drop this else and outdent its block`
+		return 2
+	}
+}
+
+func elseIf(n int) int {
+	if n < 0 {
+		return -1
+	} else if n > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func declares() int {
+	if n, ok := get(); ok {
+		return n
+	} else {
+		return -n
+	}
+}
+
+func noReturn(b bool) int {
+	var n int
+	if b {
+		n = 1
+	} else {
+		n = 2
+	}
+
+	return n
+}
@@ -0,0 +1,23 @@
+package receivernames
+
+type T struct{}
+
+func (this T) A() {} // want `receiver name should be a reflection of its identity; don't use generic names such as "this"`
+
+func (t T) B() {}
+
+func (x *T) C() {} // want `receiver name x should be consistent with previous receiver name t for T`
+
+func (t *T) D() {}
+
+func (_ T) E() {}
+
+func (T) F() {}
+
+type G[P any] struct{}
+
+func (g G[P]) A() {}
+
+func (h *G[P]) B() {} // want `receiver name h should be consistent with previous receiver name g for G`
+
+func (self G[P]) C() {} // want `receiver name should be a reflection of its identity; don't use generic names such as "self"`
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// minGoVersion is the oldest version of go that can install pinned versions of tools.
+var minGoVersion = SemVer{Major: 1, Minor: 16}
+
+// minGoToolVersion is the oldest version of go that can run the tools declared in go.mod with `go tool`.
+var minGoToolVersion = SemVer{Major: 1, Minor: 24}
+
+// toolsCacheDir returns the directory that pinned versions of tools are installed into,
+// each into its own directory named by the tool and its version.
+func toolsCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "goprecommit", "tools"), nil
+}
+
+// modTools returns the packages declared by `tool` directives in the given go.mod,
+// mapped to the version of the module that each package is required from.
+func modTools(filename string) (map[string]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := modfile.Parse(filename, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f.Tool) == 0 {
+		return nil, nil
+	}
+
+	tools := make(map[string]string)
+
+	for _, tool := range f.Tool {
+		var mod, version string
+
+		for _, req := range f.Require {
+			path := req.Mod.Path
+
+			if tool.Path != path && !strings.HasPrefix(tool.Path, path+"/") {
+				continue
+			}
+
+			if len(path) > len(mod) {
+				mod, version = path, req.Mod.Version
+			}
+		}
+
+		if version != "" {
+			tools[tool.Path] = version
+		}
+	}
+
+	return tools, nil
+}
+
+// HasModTool returns true if the go.mod of the module declares the given package as a tool.
+func (c *Config) HasModTool(pkg string) bool {
+	_, ok := c.modTools[pkg]
+	return ok
+}
+
+// toolSource is how the command of a tool is made available to a module.
+type toolSource int
+
+// Tool sources that are defined:
+const (
+	// toolGoTool runs the tool with `go tool`, as declared by a `tool` directive in go.mod.
+	toolGoTool toolSource = iota
+	// toolPinned runs the pinned version of the tool, installed into the tools cache.
+	toolPinned
+	// toolInPath runs the tool found in the PATH.
+	toolInPath
+	// toolLatest installs the tool at `latest`, and then runs it from the PATH.
+	toolLatest
+)
+
+// toolResolution is how the command of a tool would be made available to a module.
+type toolResolution struct {
+	Source toolSource
+
+	// Version is the version that the tool is pinned to, if any,
+	// even when go is too old to install it, and the tool is then taken from the PATH.
+	Version string
+
+	// Path is the binary of the tool: in the tools cache for toolPinned, where it may not be installed yet,
+	// or in the PATH for toolInPath.
+	Path string
+}
+
+// resolveTool decides how the command `bin` of the tool from `pkg` would be made available
+// to the module with the given configuration, without installing anything.
+//
+// If the module declares the tool with a `tool` directive in its go.mod, then it is run with `go tool`,
+// so that the module uses exactly the version it requires.
+// Otherwise, it is installed at the version pinned by the configuration, or else at `defaultVersion`,
+// and if there is no version at all, it is found in the PATH, or installed at `latest`.
+func resolveTool(ctx context.Context, cfg *Config, bin, pkg, defaultVersion string) (toolResolution, error) {
+	ver := goCmd.Version(ctx)
+
+	if cfg.HasModTool(pkg) {
+		if !ver.olderThan(minGoToolVersion) {
+			return toolResolution{Source: toolGoTool}, nil
+		}
+
+		Verbose(bin, "go tool needs go1.24, installing the version required by go.mod")
+	}
+
+	r := toolResolution{
+		Version: cfg.ToolVersion(bin, pkg),
+	}
+	if r.Version == "" {
+		r.Version = defaultVersion
+	}
+
+	if r.Version != "" && !ver.olderThan(minGoVersion) {
+		cacheDir, err := toolsCacheDir()
+		if err != nil {
+			return r, err
+		}
+
+		r.Source = toolPinned
+		r.Path = filepath.Join(cacheDir, bin+"@"+r.Version, bin)
+		return r, nil
+	}
+
+	if path, err := findBin(bin); err == nil {
+		r.Source = toolInPath
+		r.Path = path
+		return r, nil
+	}
+
+	r.Source = toolLatest
+	return r, nil
+}
+
+// installTool makes the command of the tool from `pkg` available to the module in the current working directory,
+// as decided by resolveTool.
+//
+// If anything fails, it will print an Error, and exit.
+func installTool(ctx context.Context, cfg *Config, c *command, pkg, defaultVersion string) {
+	// each module may make the tool available differently, so nothing is kept from the module before.
+	c.Path, c.GoTool = "", ""
+	c.binPath = once[string]{}
+
+	r, err := resolveTool(ctx, cfg, c.Bin, pkg, defaultVersion)
+	if err != nil {
+		Error("tools cache", err)
+		Exit(1)
+	}
+
+	if r.Source != toolPinned && r.Version != "" {
+		Warning(c.Bin, "cannot install pinned version ", r.Version, " before go", minGoVersion.Major, ".", minGoVersion.Minor)
+	}
+
+	switch r.Source {
+	case toolGoTool:
+		Verbose(c.Bin, "using go tool ", pkg)
+		c.GoTool = pkg
+
+	case toolPinned:
+		goCmd.InstallPinned(ctx, pkg, r.Version, r.Path)
+
+		Verbose(c.Bin, "using pinned version ", r.Version, ": ", r.Path)
+		c.Path = r.Path
+
+	case toolLatest:
+		goCmd.Install(ctx, c.Bin, pkg)
+	}
+}
+
+// ToolVersion returns the version that the tool installed as `bin` from `pkg` is pinned to,
+// either by name in the configuration, or by a `tool` directive in go.mod.
+// It returns an empty string if the tool is not pinned.
+func (c *Config) ToolVersion(bin, pkg string) string {
+	if version := c.Tools[bin]; version != "" {
+		return version
+	}
+
+	return c.modTools[pkg]
+}
@@ -4,23 +4,46 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sync"
 )
 
 type command struct {
 	Bin string
 
+	// Path is the path of the binary to run, if it is not the Bin found in the PATH.
+	Path string
+
+	// GoTool is the package of a tool declared in go.mod, which is run with `go tool` instead of the binary.
+	GoTool string
+
+	// environ, if set, returns the environment to run the binary in, from the environment of the process.
+	environ func(env []string) []string
+
 	binPath once[string]
 }
 
 func (c *command) Command(ctx context.Context, args ...string) *exec.Cmd {
-	binPath := c.binPath.Get(func() string {
-		return mustFindBin(c.Bin)
-	})
+	if c.GoTool != "" {
+		return goCmd.Command(ctx, append([]string{"tool", c.GoTool}, args...)...)
+	}
 
-	return exec.CommandContext(ctx, binPath, args...)
+	binPath := c.Path
+	if binPath == "" {
+		binPath = c.binPath.Get(func() string {
+			return mustFindBin(c.Bin)
+		})
+	}
+
+	cmd := exec.CommandContext(ctx, binPath, args...)
+	if c.environ != nil {
+		cmd.Env = c.environ(os.Environ())
+	}
+
+	return cmd
 }
 
 func (c *command) handleOutput(output []byte, err error) (string, bool) {
@@ -102,3 +125,25 @@ func (o *once[V]) Get(f func() V) V {
 
 	return o.v
 }
+
+// filepathToURI returns the given relative filepath as a relative URI reference.
+func filepathToURI(name string) string {
+	u := url.URL{
+		Path: filepath.ToSlash(name),
+	}
+
+	return u.String()
+}
+
+// mustAbs returns an absolute representation of the given filename.
+//
+// If it fails, it will print an Error, and exit.
+func mustAbs(filename string) string {
+	abs, err := filepath.Abs(filename)
+	if err != nil {
+		Error(filename, err)
+		Exit(1)
+	}
+
+	return abs
+}
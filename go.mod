@@ -1,5 +1,12 @@
 module github.com/puellanivis/goprecommit
 
-go 1.21
+go 1.23.0
 
-require github.com/puellanivis/breton v0.2.16
+require (
+	github.com/puellanivis/breton v0.2.16
+	golang.org/x/mod v0.27.0
+	golang.org/x/tools v0.36.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sync v0.16.0 // indirect